@@ -0,0 +1,271 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/go-spring/spring-base/util"
+)
+
+// alias maps a deprecated key to its replacement.
+type alias struct {
+	old string
+	new string
+}
+
+// AddAlias declares oldKey as a deprecated name of newKey, e.g. while
+// "server.addr" is being renamed to "http.listen.address". The alias
+// covers the descendants of both keys as well. Has, Lookup, Get and
+// SubKeys (and their compiled forms) accept either name and find the
+// value under either one, preferring the value set under newKey, and
+// SubKeys merges the children found under both. A Loader reports the
+// values it loads under oldKey as deprecation warnings, see
+// AliasWarnings and CheckAliases.
+//
+// It returns an error if the keys overlap, if oldKey overlaps with
+// either key of another alias, or if newKey overlaps with the deprecated
+// key of another alias, so that aliases never chain. Several aliases may
+// share the same newKey.
+func (s *Storage) AddAlias(oldKey, newKey string) error {
+	if s.frozen != nil {
+		return errFrozen("add alias " + oldKey)
+	}
+	for _, key := range []string{oldKey, newKey} {
+		if _, err := SplitPath(key); err != nil {
+			return err
+		}
+	}
+	if keyUnder(oldKey, newKey) || keyUnder(newKey, oldKey) {
+		return util.FormatError(nil, "alias %s -> %s: keys overlap", oldKey, newKey)
+	}
+	for _, a := range s.aliases {
+		if keyUnder(oldKey, a.old) || keyUnder(a.old, oldKey) ||
+			keyUnder(newKey, a.old) || keyUnder(a.old, newKey) ||
+			keyUnder(oldKey, a.new) || keyUnder(a.new, oldKey) {
+			return util.FormatError(nil, "alias %s -> %s overlaps alias %s -> %s", oldKey, newKey, a.old, a.new)
+		}
+	}
+	s.aliases = append(s.aliases, alias{old: oldKey, new: newKey})
+	return nil
+}
+
+// Aliases returns the declared aliases, deprecated key -> replacement.
+func (s *Storage) Aliases() map[string]string {
+	m := make(map[string]string, len(s.aliases))
+	for _, a := range s.aliases {
+		m[a.old] = a.new
+	}
+	return m
+}
+
+// keyUnder reports whether key is prefix or one of its descendants.
+func keyUnder(key, prefix string) bool {
+	rest, ok := strings.CutPrefix(key, prefix)
+	return ok && (rest == "" || rest[0] == '.' || rest[0] == '[')
+}
+
+// replacementOf returns the key that replaces key, if key is covered by
+// the deprecated side of an alias.
+func (s *Storage) replacementOf(key string) (string, bool) {
+	for _, a := range s.aliases {
+		if keyUnder(key, a.old) {
+			return a.new + key[len(a.old):], true
+		}
+	}
+	return "", false
+}
+
+// aliasKeys returns the names under which the value of key may be
+// stored: the current name first, then the deprecated ones.
+func (s *Storage) aliasKeys(key string) []string {
+	if r, ok := s.replacementOf(key); ok {
+		key = r
+	}
+	keys := []string{key}
+	for _, a := range s.aliases {
+		if keyUnder(key, a.new) {
+			keys = append(keys, a.old+key[len(a.new):])
+		}
+	}
+	return keys
+}
+
+// resolveAlias returns the first name of key that exists, or key itself.
+func (s *Storage) resolveAlias(key string) string {
+	if len(s.aliases) == 0 {
+		return key
+	}
+	return s.findAlias(key)
+}
+
+// findAlias is resolveAlias for a Storage with aliases.
+func (s *Storage) findAlias(key string) string {
+	for _, k := range s.aliasKeys(key) {
+		if _, ok := s.lookup(k); ok {
+			return k
+		}
+	}
+	return key
+}
+
+// aliasSubKeys merges the child keys found under every name of key.
+func (s *Storage) aliasSubKeys(key string) ([]string, error) {
+	var keys []string
+	for _, k := range s.aliasKeys(key) {
+		sub, err := s.subKeysOf(k)
+		if err != nil {
+			return nil, err
+		}
+		if sub != nil {
+			keys = append(keys, sub...)
+		}
+	}
+	if keys != nil {
+		slices.Sort(keys)
+		keys = slices.Compact(keys)
+	}
+	return keys, nil
+}
+
+// aliasWarning returns the warning for a value set under a deprecated
+// key by file, if any.
+func (s *Storage) aliasWarning(key, file string) (DeprecationWarning, bool) {
+	r, ok := s.replacementOf(key)
+	if !ok {
+		return DeprecationWarning{}, false
+	}
+	return DeprecationWarning{Key: key, File: file, Replacement: r}, true
+}
+
+// AliasWarnings returns a warning for every value stored under a
+// deprecated key (see AddAlias), with the file that set it and its
+// replacement, in tree order.
+func (s *Storage) AliasWarnings() []DeprecationWarning {
+	var warnings []DeprecationWarning
+	for _, a := range s.aliases {
+		for key, v := range s.Walk(a.old) {
+			w, _ := s.aliasWarning(key, s.fileName(v.File))
+			warnings = append(warnings, w)
+		}
+	}
+	return warnings
+}
+
+// CheckAliases reports, as *AliasConflictError joined by errors.Join,
+// every value stored under a deprecated key whose replacement holds a
+// different value, or a container.
+func (s *Storage) CheckAliases() error {
+	var errs []error
+	for _, a := range s.aliases {
+		for key, v := range s.Walk(a.old) {
+			r, _ := s.replacementOf(key)
+			n, ok := s.lookup(r)
+			if !ok || n.isLeaf() && n.Value.Kind == v.Kind && n.Value.Value == v.Value {
+				continue
+			}
+			e := &AliasConflictError{
+				Old:      key,
+				New:      r,
+				OldValue: v.Value,
+				NewValue: "<" + structOf(n.Type) + ">",
+				OldFile:  s.fileName(v.File),
+				NewFile:  s.sourceOf(r),
+			}
+			if n.isLeaf() {
+				e.NewValue = n.Value.Value
+			}
+			errs = append(errs, e)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Move relocates the subtree under oldPrefix to newPrefix, keeping the
+// files the values came from, e.g. to migrate a renamed section. Array
+// elements keep their indices, so moving an element out of an array
+// leaves a gap, and containers left empty are removed. The move is
+// atomic: it fails without changes if oldPrefix doesn't exist, if
+// newPrefix is under oldPrefix, if a moved key already exists under
+// newPrefix, or on a structural conflict.
+func (s *Storage) Move(oldPrefix, newPrefix string) error {
+	if s.frozen != nil {
+		return errFrozen("move " + oldPrefix)
+	}
+	oldPath, err := SplitPath(oldPrefix)
+	if err != nil {
+		return err
+	}
+	if _, err = SplitPath(newPrefix); err != nil {
+		return err
+	}
+	if keyUnder(newPrefix, oldPrefix) {
+		if newPrefix == oldPrefix {
+			return nil
+		}
+		return util.FormatError(nil, "cannot move %s into itself", oldPrefix)
+	}
+	if _, ok := s.lookup(oldPrefix); !ok {
+		return util.FormatError(nil, "key %s does not exist", oldPrefix)
+	}
+
+	w := s.workingCopy()
+	type entry struct {
+		key string
+		v   ValueInfo
+	}
+	var entries []entry
+	for key, v := range w.Walk(oldPrefix) {
+		entries = append(entries, entry{key, v})
+	}
+	w.prune(oldPath)
+	w.renameTransformed(oldPrefix, newPrefix)
+
+	// The values were transformed when they were set.
+	w.transformers = nil
+	for _, e := range entries {
+		key := newPrefix + e.key[len(oldPrefix):]
+		if _, ok := w.lookup(key); ok {
+			return util.FormatError(nil, "cannot move %s to %s: key %s already exists", oldPrefix, newPrefix, key)
+		}
+		path, err := SplitPath(key)
+		if err != nil {
+			return err
+		}
+		if err = w.set(key, path, e.v); err != nil {
+			return err
+		}
+	}
+	w.transformers = s.transformers
+	s.commit(w)
+	return nil
+}
+
+// prune removes the subtree at path, which must exist, along with the
+// containers it leaves empty.
+func (s *Storage) prune(path []Path) {
+	for i := len(path); i > 0; i-- {
+		parent, _ := s.mutableAt(path[:i-1])
+		s.detach(parent, path[i-1].Elem, JoinPath(path[:i]))
+		if len(parent.Data) > 0 {
+			return
+		}
+	}
+	s.dropRoot()
+}
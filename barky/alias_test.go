@@ -0,0 +1,210 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-spring/spring-base/testing/assert"
+	"github.com/go-spring/spring-base/util"
+)
+
+func TestAlias(t *testing.T) {
+
+	t.Run("declare", func(t *testing.T) {
+		s := NewStorage()
+		assert.That(t, s.AddAlias("server.addr", "http.listen.address")).Nil()
+		assert.That(t, s.AddAlias("server.tls", "http.tls")).Nil()
+		assert.That(t, s.Aliases()).Equal(map[string]string{
+			"server.addr": "http.listen.address",
+			"server.tls":  "http.tls",
+		})
+
+		for _, c := range [][3]string{
+			{"a[", "b", "invalid key"},
+			{"a", "a.b", "alias a -> a.b: keys overlap"},
+			{"a[0]", "a", "alias a\\[0\\] -> a: keys overlap"},
+			{"server.addr", "x", "alias server.addr -> x overlaps alias server.addr -> http.listen.address"},
+			{"server", "x", "overlaps alias server.addr"},
+			{"server.addr.port", "x", "overlaps alias server.addr"},
+			{"x", "server.tls.cert", "overlaps alias server.tls -> http.tls"},
+			{"y", "server", "overlaps alias server.addr -> http.listen.address"},
+			{"http", "y", "overlaps alias server.addr -> http.listen.address"},
+			{"http.tls.cert", "y", "overlaps alias server.tls -> http.tls"},
+		} {
+			assert.Error(t, s.AddAlias(c[0], c[1])).Matches(c[2])
+		}
+		assert.That(t, s.AddAlias("legacy.addr", "http.listen.address")).Nil()
+
+		err := NewBuilder().Build().AddAlias("a", "b")
+		assert.That(t, errors.Is(err, util.ErrForbiddenMethod)).True()
+	})
+
+	t.Run("resolve", func(t *testing.T) {
+		s := NewStorage()
+		old := s.AddFile("old.json")
+		cur := s.AddFile("new.json")
+		assert.That(t, s.AddAlias("server", "http.listen")).Nil()
+		assert.That(t, s.Set("server.addr", ":80", old)).Nil()
+		assert.That(t, s.Set("server.tls.cert", "a.pem", old)).Nil()
+		assert.That(t, s.Set("http.listen.tls.cert", "b.pem", cur)).Nil()
+		assert.That(t, s.Set("http.listen.tls.key", "b.key", cur)).Nil()
+
+		// The value under the new name wins, and either name finds it.
+		for _, key := range []string{"server.addr", "http.listen.addr"} {
+			assert.That(t, s.Has(key)).True()
+			assert.That(t, s.Get(key)).Equal(":80")
+		}
+		for _, key := range []string{"server.tls.cert", "http.listen.tls.cert"} {
+			v, ok := s.Lookup(key)
+			assert.That(t, ok).True()
+			assert.That(t, v.Value).Equal("b.pem")
+			assert.That(t, v.File).Equal(cur)
+		}
+		assert.That(t, s.Get("server.tls.key")).Equal("b.key")
+		assert.That(t, s.Has("server.port")).False()
+		assert.That(t, s.Get("http.listen.port", "8080")).Equal("8080")
+
+		// Child keys are merged from both names.
+		for _, key := range []string{"server", "http.listen"} {
+			keys, err := s.SubKeys(key)
+			assert.That(t, err).Nil()
+			assert.That(t, keys).Equal([]string{"addr", "tls"})
+		}
+		keys, err := s.SubKeys("server.tls")
+		assert.That(t, err).Nil()
+		assert.That(t, keys).Equal([]string{"cert", "key"})
+		keys, err = s.SubKeys("server.none")
+		assert.That(t, err).Nil()
+		assert.That(t, keys).Nil()
+		_, err = s.SubKeys("server.addr")
+		assert.That(t, errors.Is(err, ErrConflict)).True()
+
+		// Compiled keys and views resolve aliases as well.
+		k := MustCompile("http.listen.addr")
+		assert.That(t, s.GetCompiled(k)).Equal(":80")
+		keys, err = s.SubKeysCompiled(MustCompile("server.tls"))
+		assert.That(t, err).Nil()
+		assert.That(t, keys).Equal([]string{"cert", "key"})
+		assert.That(t, s.Sub("http.listen").Get("addr")).Equal(":80")
+	})
+
+	t.Run("warnings and conflicts", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"app.json":   `{"server": {"addr": ":80", "port": 80}, "http": {"listen": {"port": 8080}}}`,
+			"local.json": `{"server": {"addr": ":90"}, "http": {"listen": {"addr": ":80"}}}`,
+		})
+
+		s := NewStorage()
+		assert.That(t, s.AddAlias("server", "http.listen")).Nil()
+		l := NewLoader(s)
+		assert.That(t, l.Load(filepath.Join(dir, "app.json"))).Nil()
+		assert.That(t, l.Load(filepath.Join(dir, "local.json"))).Nil()
+
+		app, local := filepath.Join(dir, "app.json"), filepath.Join(dir, "local.json")
+		assert.That(t, l.Warnings()).Equal([]DeprecationWarning{
+			{Key: "server.addr", File: app, Replacement: "http.listen.addr"},
+			{Key: "server.port", File: app, Replacement: "http.listen.port"},
+			{Key: "server.addr", File: local, Replacement: "http.listen.addr"},
+		})
+		assert.That(t, s.AliasWarnings()).Equal([]DeprecationWarning{
+			{Key: "server.addr", File: local, Replacement: "http.listen.addr"},
+			{Key: "server.port", File: app, Replacement: "http.listen.port"},
+		})
+		assert.That(t, s.AliasWarnings()[0].String()).Equal(
+			"key server.addr (from " + local + ") is deprecated, use http.listen.addr instead")
+
+		err := s.CheckAliases()
+		assert.That(t, errors.Is(err, ErrConflict)).True()
+		var e *AliasConflictError
+		assert.That(t, errors.As(err, &e)).True()
+		assert.That(t, *e).Equal(AliasConflictError{
+			Old: "server.addr", New: "http.listen.addr",
+			OldValue: ":90", NewValue: ":80",
+			OldFile: local, NewFile: local,
+		})
+		assert.Error(t, err).Matches(`alias conflict: server.addr = ":90" \(from .*local.json\) vs http.listen.addr = ":80" \(from .*local.json\)
+alias conflict: server.port = "80" \(from .*app.json\) vs http.listen.port = "8080" \(from .*app.json\)`)
+
+		s = NewStorage()
+		assert.That(t, s.AddAlias("a", "b")).Nil()
+		assert.That(t, s.Set("a.x", "1", 0)).Nil()
+		assert.That(t, s.Set("b.y", "1", 0)).Nil()
+		assert.That(t, s.CheckAliases()).Nil()
+		assert.That(t, s.Set("b.x[0]", "1", 0)).Nil()
+		assert.Error(t, s.CheckAliases()).Matches(`a.x = "1" \(from \) vs b.x = "<array>"`)
+	})
+}
+
+func TestMove(t *testing.T) {
+	newStorage := func() *Storage {
+		s := NewStorage()
+		a := s.AddFile("a.json")
+		for key, val := range map[string]any{
+			"server.addr":         ":80",
+			"server.tls.cert":     "a.pem",
+			"server.tls.ciphers":  EmptySlice,
+			"http.debug":          true,
+			"list[0]":             "x",
+			"list[1].name":        "y",
+			"list[2]":             "z",
+			"deep.nested.only.me": "1",
+		} {
+			assert.That(t, s.Set(key, val, a)).Nil()
+		}
+		return s
+	}
+
+	s := newStorage()
+	assert.That(t, s.AddTransformer("lower", "http.**", ToLower)).Nil()
+	assert.That(t, s.Move("server", "http.listen")).Nil()
+	assert.That(t, s.Move("list[1]", "item")).Nil()
+	assert.That(t, s.Move("deep.nested.only", "only")).Nil()
+	assert.That(t, s.Move("only", "only")).Nil()
+	assert.That(t, s.RawData()).Equal(map[string]ValueInfo{
+		"http.debug":              {File: 0, Value: "true", Kind: KindBool, Raw: true},
+		"http.listen.addr":        {File: 0, Value: ":80"},
+		"http.listen.tls.cert":    {File: 0, Value: "a.pem"},
+		"http.listen.tls.ciphers": {File: 0, Value: "[]", Kind: KindEmptySlice},
+		"item.name":               {File: 0, Value: "y"},
+		"list[0]":                 {File: 0, Value: "x"},
+		"list[2]":                 {File: 0, Value: "z"},
+		"only.me":                 {File: 0, Value: "1"},
+	})
+	assertIndexed(t, s)
+
+	for _, c := range [][3]string{
+		{"server[", "x", "invalid key"},
+		{"server", "x[", "invalid key"},
+		{"server", "server.tls.x", "cannot move server into itself"},
+		{"none", "x", "key none does not exist"},
+		{"server.tls", "http.debug", "property conflict at path http.debug.cert: existing value \\(from a.json\\) vs map"},
+		{"server.tls", "list", "property conflict at path list.cert: existing array"},
+		{"server.addr", "http.debug", "cannot move server.addr to http.debug: key http.debug already exists"},
+	} {
+		s = newStorage()
+		before := s.RawData()
+		assert.Error(t, s.Move(c[0], c[1])).Matches(c[2])
+		assert.That(t, s.RawData()).Equal(before)
+	}
+
+	err := NewBuilder().Build().Move("a", "b")
+	assert.That(t, errors.Is(err, util.ErrForbiddenMethod)).True()
+}
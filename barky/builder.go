@@ -0,0 +1,146 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"github.com/go-spring/spring-base/util"
+)
+
+// frozenIndex holds the results precomputed for a frozen Storage. Key
+// lookups keep using the Storage's key index, and the child keys share
+// their strings with the tree, so the cost is the sorted keys plus one
+// string header per node and a map entry per container.
+type frozenIndex struct {
+	keys    []string               // sorted keys, as returned by Keys
+	subKeys map[*treeNode][]string // container -> sorted child keys
+}
+
+// freeze makes the Storage read-only and precomputes its results.
+func (s *Storage) freeze() {
+	if s.frozen != nil {
+		return
+	}
+	idx := &frozenIndex{
+		keys:    s.Keys(),
+		subKeys: make(map[*treeNode][]string),
+	}
+	if s.root != nil {
+		idx.add(s.root)
+	}
+	s.frozen = idx
+}
+
+// add records the sorted child keys of the containers under n.
+func (idx *frozenIndex) add(n *treeNode) {
+	if n.isLeaf() {
+		return
+	}
+	idx.subKeys[n] = util.OrderedMapKeys(n.Data)
+	for _, c := range n.Data {
+		idx.add(c)
+	}
+}
+
+// Frozen reports whether the Storage is read-only, see Builder.
+func (s *Storage) Frozen() bool {
+	return s.frozen != nil
+}
+
+// errFrozen returns the error for a mutation of a frozen Storage.
+func errFrozen(method string) error {
+	return util.FormatError(util.ErrForbiddenMethod, "%s on frozen storage", method)
+}
+
+// Builder loads and merges data into a Storage that is frozen by Build,
+// so that the result can be shared with many components, none of which
+// can modify it. Mutations of a frozen Storage fail with an error
+// matching util.ErrForbiddenMethod, and reads use indexes computed once
+// by Build. A frozen Storage is safe for concurrent use.
+type Builder struct {
+	storage *Storage
+	loader  *Loader
+}
+
+// NewBuilder creates a Builder for a new, empty Storage.
+func NewBuilder() *Builder {
+	s := NewStorage()
+	return &Builder{storage: s, loader: NewLoader(s)}
+}
+
+// Loader returns the Loader used by Load and LoadKV, e.g. to register
+// decoders or set options.
+func (b *Builder) Loader() *Loader {
+	return b.loader
+}
+
+// Load loads a file and its imports, see Loader.Load.
+func (b *Builder) Load(file string) error {
+	return b.loader.Load(file)
+}
+
+// LoadKV loads entries from a KVSource, see Loader.LoadKV.
+func (b *Builder) LoadKV(name string, src KVSource, prefix string) error {
+	return b.loader.LoadKV(name, src, prefix)
+}
+
+// AddFile registers a file name, see Storage.AddFile.
+func (b *Builder) AddFile(file string) int8 {
+	return b.storage.AddFile(file)
+}
+
+// TryAddFile registers a file name, see Storage.TryAddFile.
+func (b *Builder) TryAddFile(file string) (int8, error) {
+	return b.storage.TryAddFile(file)
+}
+
+// Set sets a value, see Storage.Set.
+func (b *Builder) Set(key string, val any, file int8) error {
+	return b.storage.Set(key, val, file)
+}
+
+// SetAll sets a batch of values, see Storage.SetAll.
+func (b *Builder) SetAll(m map[string]any, file int8) error {
+	return b.storage.SetAll(m, file)
+}
+
+// SetDefaults fills in struct tag defaults, see Storage.SetDefaults.
+func (b *Builder) SetDefaults(v any) error {
+	return b.storage.SetDefaults(v)
+}
+
+// AddTransformer registers a transformer, see Storage.AddTransformer.
+func (b *Builder) AddTransformer(name, pattern string, fn Transformer) error {
+	return b.storage.AddTransformer(name, pattern, fn)
+}
+
+// AddAlias declares a deprecated key, see Storage.AddAlias.
+func (b *Builder) AddAlias(oldKey, newKey string) error {
+	return b.storage.AddAlias(oldKey, newKey)
+}
+
+// Move relocates a subtree, see Storage.Move.
+func (b *Builder) Move(oldPrefix, newPrefix string) error {
+	return b.storage.Move(oldPrefix, newPrefix)
+}
+
+// Build freezes the Storage and returns it. Further mutations through
+// the Builder fail as well, and calling Build again returns the same
+// Storage.
+func (b *Builder) Build() *Storage {
+	b.storage.freeze()
+	return b.storage
+}
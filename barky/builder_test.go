@@ -0,0 +1,172 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-spring/spring-base/testing/assert"
+	"github.com/go-spring/spring-base/util"
+)
+
+func TestBuilder(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.json": `{"name": " app ", "db": {"hosts": ["a", "b"]}, "empty": []}`,
+		"kv/port":  "8080",
+	})
+
+	b := NewBuilder()
+	assert.That(t, b.AddTransformer("trim", "**", TrimSpace)).Nil()
+	assert.That(t, b.Load(filepath.Join(dir, "app.json"))).Nil()
+	assert.That(t, b.LoadKV("kv", NewDirSource(filepath.Join(dir, "kv")), "/")).Nil()
+	file := b.AddFile("code")
+	assert.That(t, b.Set("debug", true, file)).Nil()
+	assert.That(t, b.SetAll(map[string]any{"x.y": "1"}, file)).Nil()
+	assert.That(t, b.SetDefaults(struct {
+		Level string `json:"level" default:"info"`
+	}{})).Nil()
+	assert.That(t, len(b.Loader().Layers())).Equal(2)
+
+	s := b.Build()
+	assert.That(t, s.Frozen()).True()
+	assert.That(t, b.Build() == s).True()
+	assert.That(t, NewStorage().Frozen()).False()
+
+	t.Run("reads", func(t *testing.T) {
+		assert.That(t, s.Keys()).Equal([]string{"db.hosts[0]", "db.hosts[1]", "debug", "level", "name", "port", "x.y"})
+		assert.That(t, s.Get("name")).Equal("app")
+		assert.That(t, s.Get("none", "def")).Equal("def")
+		assert.That(t, s.Has("db.hosts")).True()
+		assert.That(t, s.Has("db.hosts[2]")).False()
+		assert.That(t, s.Has("")).False()
+		assert.That(t, s.GetCompiled(MustCompile("db.hosts[1]"))).Equal("b")
+		assert.That(t, s.HasCompiled(MustCompile("db.x"))).False()
+
+		v, ok := s.Lookup("empty")
+		assert.That(t, ok).True()
+		assert.That(t, v.Kind).Equal(KindEmptySlice)
+
+		subKeys, err := s.SubKeys("")
+		assert.That(t, err).Nil()
+		assert.That(t, subKeys).Equal([]string{"db", "debug", "empty", "level", "name", "port", "x"})
+		subKeys, err = s.SubKeys("db.hosts")
+		assert.That(t, err).Nil()
+		assert.That(t, subKeys).Equal([]string{"0", "1"})
+		subKeys[0] = "changed"
+		subKeys, _ = s.SubKeys("db.hosts")
+		assert.That(t, subKeys).Equal([]string{"0", "1"})
+		subKeys, err = s.SubKeys("empty")
+		assert.That(t, err).Nil()
+		assert.That(t, subKeys).Equal([]string{})
+		_, err = s.SubKeys("name")
+		assert.That(t, errors.Is(err, ErrConflict)).True()
+		_, err = s.SubKeys("db[0]")
+		assert.That(t, errors.Is(err, ErrConflict)).True()
+		subKeys, err = s.SubKeys("none")
+		assert.That(t, err).Nil()
+		assert.That(t, subKeys).Nil()
+
+		keys := s.Keys()
+		keys[0] = "changed"
+		assert.That(t, s.Keys()[0]).Equal("db.hosts[0]")
+
+		assert.That(t, s.Sub("db").Get("hosts[0]")).Equal("a")
+		assert.That(t, s.TransformedBy("name")).Equal([]string{"trim"})
+	})
+
+	t.Run("mutations", func(t *testing.T) {
+		err := s.Set("name", "x", 0)
+		assert.That(t, errors.Is(err, util.ErrForbiddenMethod)).True()
+		assert.Error(t, err).Matches("set key name on frozen storage: forbidden method")
+
+		err = b.Set("new", "x", 0)
+		assert.That(t, errors.Is(err, util.ErrForbiddenMethod)).True()
+		err = s.SetCompiled(MustCompile("new"), "x", 0)
+		assert.That(t, errors.Is(err, util.ErrForbiddenMethod)).True()
+		err = s.SetAll(map[string]any{"new": "x"}, 0)
+		assert.That(t, errors.Is(err, util.ErrForbiddenMethod)).True()
+		err = s.AddTransformer("t", "**", TrimSpace)
+		assert.That(t, errors.Is(err, util.ErrForbiddenMethod)).True()
+		err = s.Transform()
+		assert.That(t, errors.Is(err, util.ErrForbiddenMethod)).True()
+		err = b.Load(filepath.Join(dir, "app.json"))
+		assert.That(t, errors.Is(err, util.ErrForbiddenMethod)).True()
+
+		idx, err := s.TryAddFile("code")
+		assert.That(t, err).Nil()
+		assert.That(t, idx).Equal(file)
+		_, err = s.TryAddFile("other")
+		assert.That(t, errors.Is(err, util.ErrForbiddenMethod)).True()
+		assert.That(t, s.AddFile("other")).Equal(int8(-1))
+		files := s.RawFile()
+		files["other"] = 9
+		_, ok := s.RawFile()["other"]
+		assert.That(t, ok).False()
+
+		err = s.SetDefaults(struct {
+			Name string `json:"new" default:"x"`
+		}{})
+		assert.That(t, errors.Is(err, util.ErrForbiddenMethod)).True()
+		assert.That(t, s.Has("new")).False()
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		s := NewBuilder().Build()
+		assert.That(t, s.Keys()).Equal([]string{})
+		assert.That(t, s.Has("a")).False()
+		subKeys, err := s.SubKeys("")
+		assert.That(t, err).Nil()
+		assert.That(t, subKeys).Nil()
+	})
+}
+
+func BenchmarkFrozen(b *testing.B) {
+	const n = 100_000
+	keys := benchKeys(n)
+	s := newBenchStorage(keys)
+	f := newBenchStorage(keys)
+	f.freeze()
+	const key = "app.services.svc100.endpoints[3].config.option12"
+	const parent = "app.services.svc100.endpoints[3].config"
+
+	for _, c := range []struct {
+		name string
+		s    *Storage
+	}{{"mutable", s}, {"frozen", f}} {
+		b.Run("get-"+c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				c.s.Get(key)
+			}
+		})
+		b.Run("keys-"+c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				c.s.Keys()
+			}
+		})
+		b.Run("sub-keys-"+c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				_, _ = c.s.SubKeys(parent)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"slices"
+	"sync/atomic"
+
+	"github.com/go-spring/spring-base/util"
+)
+
+// CompiledKey is a hierarchical key parsed once by Compile, so that it
+// can be used repeatedly with the *Compiled methods of Storage without
+// parsing it again. It also remembers the node it was last found at, so
+// that looking it up again in the same Storage needs neither a tree walk
+// nor a hash. A CompiledKey is safe for concurrent use.
+type CompiledKey struct {
+	key  string
+	path []Path
+	hit  atomic.Pointer[compiledHit]
+}
+
+// compiledHit is the node a CompiledKey was last found at. Nodes are
+// never removed from a tree in place: patches and moves replace the
+// whole tree. So the hit stays valid as long as the root is the same.
+type compiledHit struct {
+	root *treeNode
+	node *treeNode
+}
+
+// Compile parses a hierarchical key for repeated use.
+// It returns a *PathSyntaxError if the key is malformed.
+func Compile(key string) (*CompiledKey, error) {
+	path, err := SplitPath(key)
+	if err != nil {
+		return nil, err
+	}
+	return &CompiledKey{key: key, path: path}, nil
+}
+
+// MustCompile is like Compile but panics if the key is malformed.
+// It is intended for keys known at compile time.
+func MustCompile(key string) *CompiledKey {
+	k, err := Compile(key)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+// String returns the original key.
+func (k *CompiledKey) String() string {
+	return k.key
+}
+
+// Path returns a copy of the parsed path.
+func (k *CompiledKey) Path() []Path {
+	return slices.Clone(k.path)
+}
+
+// lookupCompiled finds the tree node for a compiled key.
+func (s *Storage) lookupCompiled(k *CompiledKey) (*treeNode, bool) {
+	if len(s.aliases) > 0 {
+		return s.lookup(s.resolveAlias(k.key))
+	}
+	if h := k.hit.Load(); h != nil && h.root == s.root && h.root != nil {
+		return h.node, true
+	}
+	n, ok := s.walkCompiled(k)
+	if ok {
+		k.hit.Store(&compiledHit{root: s.root, node: n})
+	}
+	return n, ok
+}
+
+// walkCompiled finds the node of a compiled key in the index, or in the
+// tree if the Storage is compact.
+func (s *Storage) walkCompiled(k *CompiledKey) (*treeNode, bool) {
+	if s.index != nil {
+		return s.indexed(k.key)
+	}
+	n := s.root
+	if n == nil {
+		return nil, false
+	}
+	for _, p := range k.path {
+		if n.isLeaf() || p.Type != n.Type {
+			return nil, false
+		}
+		if n = n.Data[p.Elem]; n == nil {
+			return nil, false
+		}
+	}
+	return n, true
+}
+
+// HasCompiled is like Has, but takes a compiled key.
+func (s *Storage) HasCompiled(k *CompiledKey) bool {
+	_, ok := s.lookupCompiled(k)
+	return ok
+}
+
+// LookupCompiled is like Lookup, but takes a compiled key.
+func (s *Storage) LookupCompiled(k *CompiledKey) (ValueInfo, bool) {
+	return leafValue(s.lookupCompiled(k))
+}
+
+// GetCompiled is like Get, but takes a compiled key.
+func (s *Storage) GetCompiled(k *CompiledKey, def ...string) string {
+	n, ok := s.lookupCompiled(k)
+	return nodeValueOrDefault(n, ok, def)
+}
+
+// SubKeysCompiled is like SubKeys, but takes a compiled key.
+func (s *Storage) SubKeysCompiled(k *CompiledKey) ([]string, error) {
+	if len(s.aliases) > 0 {
+		return s.aliasSubKeys(k.key)
+	}
+	return s.subKeys(k.key, k.path)
+}
+
+// SetCompiled is like Set, but takes a compiled key.
+func (s *Storage) SetCompiled(k *CompiledKey, val any, file int8) error {
+	if err := s.checkDefaults(k.key, file); err != nil {
+		return err
+	}
+	info, err := newValueInfo(val, file)
+	if err != nil {
+		return util.FormatError(err, "invalid value for key %s", k.key)
+	}
+	return s.set(k.key, k.path, info)
+}
@@ -0,0 +1,160 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-spring/spring-base/testing/assert"
+)
+
+func TestCompiledKey(t *testing.T) {
+
+	t.Run("compile", func(t *testing.T) {
+		k, err := Compile("a.b[0]")
+		assert.That(t, err).Nil()
+		assert.That(t, k.String()).Equal("a.b[0]")
+		assert.That(t, k.Path()).Equal([]Path{
+			{PathTypeKey, "a"}, {PathTypeKey, "b"}, {PathTypeIndex, "0"},
+		})
+
+		_, err = Compile("a..b")
+		assert.That(t, errors.Is(err, ErrInvalidPath)).True()
+
+		assert.Panic(t, func() { MustCompile("a[") }, "unclosed")
+	})
+
+	t.Run("storage", func(t *testing.T) {
+		s := NewStorage()
+		fileID := s.AddFile("test.yaml")
+
+		flag := MustCompile("features.login.enabled")
+		assert.That(t, s.HasCompiled(flag)).False()
+		assert.That(t, s.GetCompiled(flag, "false")).Equal("false")
+		_, ok := s.LookupCompiled(flag)
+		assert.That(t, ok).False()
+		subKeys, err := s.SubKeysCompiled(flag)
+		assert.That(t, err).Nil()
+		assert.That(t, subKeys).Nil()
+
+		assert.That(t, s.SetCompiled(flag, true, fileID)).Nil()
+		assert.That(t, s.HasCompiled(flag)).True()
+		assert.That(t, s.GetCompiled(flag)).Equal("true")
+		assert.That(t, s.Get("features.login.enabled")).Equal("true")
+		v, ok := s.LookupCompiled(flag)
+		assert.That(t, ok).True()
+		assert.That(t, v).Equal(ValueInfo{File: fileID, Value: "true", Kind: KindBool, Raw: true})
+
+		login := MustCompile("features.login")
+		assert.That(t, s.HasCompiled(login)).True()
+		assert.That(t, s.GetCompiled(login, "def")).Equal("def")
+		subKeys, err = s.SubKeysCompiled(login)
+		assert.That(t, err).Nil()
+		assert.That(t, subKeys).Equal([]string{"enabled"})
+
+		_, err = s.SubKeysCompiled(flag)
+		assert.That(t, errors.Is(err, ErrConflict)).True()
+		err = s.SetCompiled(MustCompile("features.login.enabled.x"), "1", fileID)
+		assert.Error(t, err).Matches("property conflict at path features.login.enabled.x")
+		err = s.SetCompiled(MustCompile("features[0]"), "1", fileID)
+		assert.That(t, errors.Is(err, ErrConflict)).True()
+		err = s.SetCompiled(flag, 1+2i, fileID)
+		assert.Error(t, err).Matches("invalid value for key features.login.enabled")
+
+		assert.That(t, s.HasCompiled(MustCompile("features[0]"))).False()
+		assert.That(t, s.HasCompiled(MustCompile("features.login.enabled.x"))).False()
+	})
+
+	t.Run("remembered node", func(t *testing.T) {
+		for _, s := range []*Storage{NewStorage(), NewCompactStorage()} {
+			k := MustCompile("a.b")
+			assert.That(t, s.Set("a.b", "1", 0)).Nil()
+			assert.That(t, s.GetCompiled(k)).Equal("1")
+
+			// Values are updated in place.
+			assert.That(t, s.Set("a.b", "2", 0)).Nil()
+			assert.That(t, s.GetCompiled(k)).Equal("2")
+
+			// Patches and moves replace the tree.
+			assert.That(t, s.ApplyMergePatch("p", []byte(`{"a":{"b":null}}`))).Nil()
+			assert.That(t, s.HasCompiled(k)).False()
+			assert.That(t, s.ApplyMergePatch("p", []byte(`{"a":{"b":3}}`))).Nil()
+			assert.That(t, s.GetCompiled(k)).Equal("3")
+			assert.That(t, s.Move("a", "c")).Nil()
+			assert.That(t, s.HasCompiled(k)).False()
+
+			// The same key can be used with other storages.
+			o := NewStorage()
+			assert.That(t, o.Set("a.b", "4", 0)).Nil()
+			assert.That(t, o.GetCompiled(k)).Equal("4")
+			assert.That(t, s.Set("a.b", "5", 0)).Nil()
+			assert.That(t, s.GetCompiled(k)).Equal("5")
+			assert.That(t, o.GetCompiled(k)).Equal("4")
+		}
+	})
+}
+
+func BenchmarkCompiledKey(b *testing.B) {
+	const n = 100_000
+	s := newBenchStorage(benchKeys(n))
+	const key = "app.services.svc100.endpoints[3].config.option12"
+	k := MustCompile(key)
+
+	b.Run("get", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			s.Get(key)
+		}
+	})
+
+	b.Run("get-compiled", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			s.GetCompiled(k)
+		}
+	})
+
+	b.Run("sub-keys", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			_, _ = s.SubKeys("app.services.svc100.endpoints[3].config")
+		}
+	})
+
+	b.Run("sub-keys-compiled", func(b *testing.B) {
+		sk := MustCompile("app.services.svc100.endpoints[3].config")
+		b.ReportAllocs()
+		for b.Loop() {
+			_, _ = s.SubKeysCompiled(sk)
+		}
+	})
+
+	b.Run("set", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			_ = s.Set(key, "value", 0)
+		}
+	})
+
+	b.Run("set-compiled", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			_ = s.SetCompiled(k, "value", 0)
+		}
+	})
+}
@@ -0,0 +1,253 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-spring/spring-base/util"
+)
+
+// DefaultsFile is the file name under which SetDefaults registers the
+// default values, so that their provenance reads "<defaults>".
+const DefaultsFile = "<defaults>"
+
+// DefaultsOf collects the default values declared by the `default` tags
+// of a struct (or a pointer to one) into a flattened map.
+//
+// The key of a field is its `json` tag name if present, otherwise the
+// field name; fields tagged `json:"-"` and unexported fields are skipped.
+// Nested structs and pointers to structs are traversed, and embedded
+// structs without a `json` name contribute their fields to the parent,
+// even if the embedded type is unexported. Slices of structs are
+// traversed for the elements present in v, so a default element layout
+// can be declared by passing a value with pre-sized slices. A nil
+// pointer to a struct type that is already being traversed, as in a
+// linked list, is not followed.
+//
+// For a slice of scalars, the tag lists the elements separated by
+// commas, and an empty tag yields EmptySlice. Defaults of bool, integer
+// and float fields are parsed and kept typed; defaults of other types
+// (including named types such as time.Duration) are kept as strings.
+func DefaultsOf(v any) (map[string]any, error) {
+	c := &defaultsCollector{m: make(map[string]any)}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv = reflect.Zero(rv.Type().Elem())
+			continue
+		}
+		c.ptrs = append(c.ptrs, rv.Pointer())
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, util.FormatError(nil, "defaults of %T error: not a struct", v)
+	}
+	if err := c.structDefaults(rv, ""); err != nil {
+		return nil, util.FormatError(err, "defaults of %T error", v)
+	}
+	return c.m, nil
+}
+
+// defaultsCollector collects the defaults of a struct.
+type defaultsCollector struct {
+	m     map[string]any
+	types []reflect.Type // struct types being traversed
+	ptrs  []uintptr      // pointers being traversed
+}
+
+// structDefaults collects the defaults of struct v under the given key.
+func (c *defaultsCollector) structDefaults(v reflect.Value, key string) error {
+	t := v.Type()
+	c.types = append(c.types, t)
+	defer func() { c.types = c.types[:len(c.types)-1] }()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		// Like encoding/json, promote the fields of unexported embedded structs.
+		if !f.IsExported() && (!f.Anonymous || indirectType(f.Type).Kind() != reflect.Struct) {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			tag, _, _ = strings.Cut(tag, ",")
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		} else if f.Anonymous {
+			name = ""
+		}
+		fieldKey := name
+		if key != "" && name != "" {
+			fieldKey = key + "." + name
+		} else if name == "" {
+			fieldKey = key
+		}
+		if err := c.fieldDefaults(v.Field(i), f, fieldKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldDefaults collects the defaults of a struct field.
+func (c *defaultsCollector) fieldDefaults(v reflect.Value, f reflect.StructField, key string) error {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			if slices.Contains(c.types, indirectType(v.Type())) {
+				return nil // a recursive type, e.g. the end of a linked list
+			}
+			v = reflect.Zero(v.Type().Elem())
+			continue
+		}
+		p := v.Pointer()
+		if slices.Contains(c.ptrs, p) {
+			return nil // a cyclic value
+		}
+		c.ptrs = append(c.ptrs, p)
+		defer func() { c.ptrs = c.ptrs[:len(c.ptrs)-1] }()
+		v = v.Elem()
+	}
+	tag, hasTag := f.Tag.Lookup("default")
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return c.structDefaults(v, key)
+	case reflect.Slice, reflect.Array:
+		if elem := indirectType(v.Type().Elem()); elem.Kind() == reflect.Struct {
+			for i := range v.Len() {
+				if err := c.fieldDefaults(v.Index(i), f, key+"["+strconv.Itoa(i)+"]"); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if !hasTag {
+			return nil
+		}
+		if tag == "" {
+			c.m[key] = EmptySlice
+			return nil
+		}
+		for i, s := range strings.Split(tag, ",") {
+			val, err := parseDefault(v.Type().Elem(), strings.TrimSpace(s))
+			if err != nil {
+				return util.FormatError(err, "invalid default for field %s", f.Name)
+			}
+			c.m[key+"["+strconv.Itoa(i)+"]"] = val
+		}
+	default:
+		if !hasTag {
+			return nil
+		}
+		val, err := parseDefault(v.Type(), tag)
+		if err != nil {
+			return util.FormatError(err, "invalid default for field %s", f.Name)
+		}
+		c.m[key] = val
+	}
+	return nil
+}
+
+// indirectType returns the type pointed to by t, following pointers.
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// parseDefault parses a default value for a field of type t. Values of
+// unnamed bool and numeric types are parsed, everything else is kept
+// as a string.
+func parseDefault(t reflect.Type, s string) (any, error) {
+	t = indirectType(t)
+	if t.PkgPath() != "" {
+		return s, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return strconv.ParseBool(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(s, 10, t.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(s, 10, t.Bits())
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(s, t.Bits())
+	default:
+		return s, nil
+	}
+}
+
+// SetDefaults must be called after all files are loaded: it fills the
+// keys that are still missing with the defaults declared by the struct v
+// (see DefaultsOf), and from then on Set, SetAll and SetCompiled fail
+// for every file but DefaultsFile.
+//
+// The defaults are registered under DefaultsFile, so that Has reports
+// true for defaulted keys and their provenance shows which values are
+// implicit. A default is only stored where no value exists, and it is
+// skipped if it conflicts with the structure of existing data, e.g. when
+// a file sets "servers: []". Defaults never add elements to a list that
+// already exists, so a list set by a file is not extended with default
+// elements, although the missing fields of its struct elements are
+// filled. SetDefaults may be called several times.
+func (s *Storage) SetDefaults(v any) error {
+	m, err := DefaultsOf(v)
+	if err != nil {
+		return err
+	}
+	file, err := s.TryAddFile(DefaultsFile)
+	if err != nil {
+		return err
+	}
+	// Decide before setting anything, so that the lists created by
+	// defaults do not look like existing ones.
+	var keys []string
+	for _, key := range util.OrderedMapKeys(m) {
+		if !s.Has(key) && !s.extendsList(key) {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		if err = s.Set(key, m[key], file); err != nil && !errors.Is(err, ErrConflict) {
+			return err
+		}
+	}
+	return nil
+}
+
+// extendsList reports whether setting key would add an element to a
+// list that exists in the Storage.
+func (s *Storage) extendsList(key string) bool {
+	path, err := SplitPath(key)
+	if err != nil {
+		return false // reported by Set
+	}
+	for i, p := range path {
+		if p.Type == PathTypeIndex && s.Has(JoinPath(path[:i])) && !s.Has(JoinPath(path[:i+1])) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-base/testing/assert"
+)
+
+type defaultsServer struct {
+	Host string `json:"host" default:"0.0.0.0"`
+	Port int    `json:"port" default:"8080"`
+}
+
+type defaultsLog struct {
+	Level string `json:"level" default:"info"`
+}
+
+type defaultsConfig struct {
+	defaultsLog
+	Name    string           `json:"name,omitempty" default:"app"`
+	Debug   bool             `json:"debug" default:"false"`
+	Ratio   float64          `default:"0.5"`
+	Timeout time.Duration    `json:"timeout" default:"5s"`
+	Tags    []string         `json:"tags" default:"a, b"`
+	Ports   []uint16         `json:"ports" default:""`
+	DB      *defaultsServer  `json:"db"`
+	Servers []defaultsServer `json:"servers"`
+	Ignored string           `json:"-" default:"x"`
+	NoTag   string           `json:"no_tag"`
+	private string           `default:"x"`
+}
+
+func TestDefaults(t *testing.T) {
+
+	t.Run("defaults of", func(t *testing.T) {
+		m, err := DefaultsOf(&defaultsConfig{Servers: make([]defaultsServer, 2)})
+		assert.That(t, err).Nil()
+		assert.That(t, m).Equal(map[string]any{
+			"level":           "info",
+			"name":            "app",
+			"debug":           false,
+			"Ratio":           0.5,
+			"timeout":         "5s",
+			"tags[0]":         "a",
+			"tags[1]":         "b",
+			"ports":           EmptySlice,
+			"db.host":         "0.0.0.0",
+			"db.port":         int64(8080),
+			"servers[0].host": "0.0.0.0",
+			"servers[0].port": int64(8080),
+			"servers[1].host": "0.0.0.0",
+			"servers[1].port": int64(8080),
+		})
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := DefaultsOf(1)
+		assert.Error(t, err).Matches("defaults of int error: not a struct")
+
+		_, err = DefaultsOf(struct {
+			Port int `default:"http"`
+		}{})
+		assert.Error(t, err).Matches(`invalid default for field Port: strconv.ParseInt: parsing "http": invalid syntax`)
+
+		_, err = DefaultsOf(struct {
+			Ports []uint8 `default:"1,300"`
+		}{})
+		assert.Error(t, err).Matches(`invalid default for field Ports: .* value out of range`)
+
+		err = NewStorage().SetDefaults("abc")
+		assert.Error(t, err).Matches("not a struct")
+	})
+
+	t.Run("set defaults", func(t *testing.T) {
+		s := NewStorage()
+		file := s.AddFile("app.json")
+		assert.That(t, s.Set("name", "demo", file)).Nil()
+		assert.That(t, s.Set("db.port", 3306, file)).Nil()
+		assert.That(t, s.Set("tags", EmptySlice, file)).Nil()
+		assert.That(t, s.Set("servers[0]", "x", file)).Nil()
+
+		err := s.SetDefaults(defaultsConfig{Servers: make([]defaultsServer, 1)})
+		assert.That(t, err).Nil()
+		assert.That(t, s.Data()).Equal(map[string]string{
+			"level":      "info",
+			"name":       "demo",
+			"debug":      "false",
+			"Ratio":      "0.5",
+			"timeout":    "5s",
+			"db.host":    "0.0.0.0",
+			"db.port":    "3306",
+			"servers[0]": "x",
+		})
+		assert.That(t, s.Has("ports")).True()
+		assert.That(t, s.Has("tags[0]")).False()
+
+		v, _ := s.Lookup("db.host")
+		assert.That(t, s.fileName(v.File)).Equal(DefaultsFile)
+		v, _ = s.Lookup("db.port")
+		assert.That(t, s.fileName(v.File)).Equal("app.json")
+
+		err = s.Set("db.host.x", "1", s.AddFile(DefaultsFile))
+		assert.Error(t, err).Matches(`existing value \(from <defaults>\) vs map`)
+	})
+
+	t.Run("files after defaults", func(t *testing.T) {
+		s := NewStorage()
+		assert.That(t, s.SetDefaults(defaultsConfig{})).Nil()
+		file := s.AddFile("app.json")
+		err := s.Set("tags[0]", "x", file)
+		assert.Error(t, err).Matches("set key tags\\[0\\] error: defaults are already set")
+		err = s.SetCompiled(MustCompile("name"), "x", file)
+		assert.Error(t, err).Matches("set key name error: defaults are already set")
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{"app.json": `{"port": 9090}`})
+		err = NewLoader(s).Load(filepath.Join(dir, "app.json"))
+		assert.Error(t, err).Matches("load file .*app.json error: set key port error: defaults are already set")
+		assert.That(t, s.Get("tags[1]")).Equal("b")
+
+		// Defaults can still be added, and patches still apply.
+		assert.That(t, s.SetDefaults(struct {
+			Extra string `json:"extra" default:"1"`
+		}{})).Nil()
+		assert.That(t, s.ApplyMergePatch("p", []byte(`{"tags": ["x"]}`))).Nil()
+		assert.That(t, s.Get("tags[0]")).Equal("x")
+		assert.That(t, s.Has("tags[1]")).False()
+	})
+	t.Run("existing lists", func(t *testing.T) {
+		s := NewStorage()
+		file := s.AddFile("app.json")
+		assert.That(t, s.Set("tags[0]", "x", file)).Nil()
+		assert.That(t, s.Set("servers[0].host", "example.com", file)).Nil()
+
+		err := s.SetDefaults(defaultsConfig{Servers: make([]defaultsServer, 2)})
+		assert.That(t, err).Nil()
+		assert.That(t, s.Has("tags[1]")).False()
+		assert.That(t, s.Has("servers[1]")).False()
+		assert.That(t, s.Get("tags[0]")).Equal("x")
+		assert.That(t, s.Get("servers[0].host")).Equal("example.com")
+		assert.That(t, s.Get("servers[0].port")).Equal("8080")
+	})
+
+	t.Run("recursive types", func(t *testing.T) {
+		type node struct {
+			Name     string  `default:"n"`
+			Next     *node   `json:"next"`
+			Children []*node `json:"children"`
+		}
+		m, err := DefaultsOf(&node{Next: &node{}})
+		assert.That(t, err).Nil()
+		assert.That(t, m).Equal(map[string]any{
+			"Name":      "n",
+			"next.Name": "n",
+		})
+
+		n := &node{}
+		n.Next = n
+		m, err = DefaultsOf(n)
+		assert.That(t, err).Nil()
+		assert.That(t, m).Equal(map[string]any{"Name": "n"})
+	})
+}
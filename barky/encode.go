@@ -0,0 +1,90 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/go-spring/spring-base/util"
+)
+
+// maxArrayGap is the number of missing elements Unflatten accepts in
+// an array beyond the ones it holds, so that a key such as "a[1000000000]"
+// cannot make it allocate a huge, almost empty slice.
+const maxArrayGap = 1 << 16
+
+// Unflatten rebuilds the nested structure described by the Storage.
+// Maps become map[string]any, arrays become []any (missing elements
+// are nil), and leaves become their typed form (see ValueInfo.Typed),
+// so booleans and numbers keep their original types. It returns an
+// error if an array is so sparse that it would have more than 65536
+// missing elements, e.g. for a lone "a[1000000000]".
+func (s *Storage) Unflatten() (any, error) {
+	if s.root == nil {
+		return map[string]any{}, nil
+	}
+	return s.unflatten(s.root, "")
+}
+
+// unflatten rebuilds the subtree rooted at n, whose flattened key is key.
+func (s *Storage) unflatten(n *treeNode, key string) (any, error) {
+	if n.isLeaf() {
+		return n.Value.Typed(), nil
+	}
+	if n.Type == PathTypeIndex {
+		size, limit := 0, len(n.Data)+maxArrayGap
+		for elem := range n.Data {
+			i, err := strconv.Atoi(elem)
+			if err != nil {
+				return nil, util.FormatError(err, "invalid index %q", childKey(key, n.Type, elem))
+			}
+			if i >= limit {
+				return nil, util.FormatError(nil, "index %q is too large for an array of %d elements", childKey(key, n.Type, elem), len(n.Data))
+			}
+			size = max(size, i+1)
+		}
+		arr := make([]any, size)
+		for elem, c := range n.Data {
+			i, _ := strconv.Atoi(elem)
+			v, err := s.unflatten(c, childKey(key, n.Type, elem))
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = v
+		}
+		return arr, nil
+	}
+	m := make(map[string]any, len(n.Data))
+	for elem, c := range n.Data {
+		v, err := s.unflatten(c, childKey(key, n.Type, elem))
+		if err != nil {
+			return nil, err
+		}
+		m[elem] = v
+	}
+	return m, nil
+}
+
+// MarshalJSON encodes the nested structure returned by Unflatten.
+func (s *Storage) MarshalJSON() ([]byte, error) {
+	v, err := s.Unflatten()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
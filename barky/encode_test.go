@@ -0,0 +1,101 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/spring-base/testing/assert"
+)
+
+func TestStorageJSON(t *testing.T) {
+
+	t.Run("empty", func(t *testing.T) {
+		b, err := json.Marshal(NewStorage())
+		assert.That(t, err).Nil()
+		assert.That(t, string(b)).Equal(`{}`)
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		const doc = `{"a":{"b":[1,"2",true,null,[],{}],"c":1.50},"port":8080,"s":"8080"}`
+
+		f := NewFlattener()
+		f.KeepTypes = true
+
+		s := NewStorage()
+		fileID := s.AddFile("test.json")
+		err := f.FlattenReader(strings.NewReader(doc), func(key string, value any) error {
+			return s.Set(key, value, fileID)
+		})
+		assert.That(t, err).Nil()
+
+		v, _ := s.Lookup("port")
+		assert.That(t, v.Kind).Equal(KindInt)
+		v, _ = s.Lookup("s")
+		assert.That(t, v.Kind).Equal(KindString)
+		v, _ = s.Lookup("a.b[2]")
+		assert.That(t, v.Kind).Equal(KindBool)
+
+		b, err := json.Marshal(s)
+		assert.That(t, err).Nil()
+		assert.That(t, string(b)).Equal(doc)
+	})
+
+	t.Run("sparse array", func(t *testing.T) {
+		s := NewStorage()
+		assert.That(t, s.Set("[2].a", 3.5, 0)).Nil()
+		v, err := s.Unflatten()
+		assert.That(t, err).Nil()
+		assert.That(t, v).Equal([]any{nil, nil, map[string]any{"a": 3.5}})
+	})
+
+	t.Run("index too large", func(t *testing.T) {
+		s := NewStorage()
+		assert.That(t, s.Set("a[1000000000]", 1, 0)).Nil()
+		_, err := s.MarshalJSON()
+		assert.Error(t, err).Matches(`index "a\[1000000000\]" is too large for an array of 1 elements`)
+
+		s = NewStorage()
+		assert.That(t, s.Set("a[9223372036854775807]", 1, 0)).Nil()
+		_, err = s.MarshalJSON()
+		assert.Error(t, err).Matches(`index "a\[9223372036854775807\]" is too large`)
+
+		s = NewStorage()
+		assert.That(t, s.Set("a[18446744073709551615]", 1, 0)).Nil()
+		_, err = s.MarshalJSON()
+		assert.Error(t, err).Matches(`invalid index "a\[18446744073709551615\]"`)
+	})
+
+	t.Run("flatten map with types", func(t *testing.T) {
+		f := NewFlattener()
+		f.KeepTypes = true
+		m := f.FlattenMap(map[string]any{
+			"a": map[string]any{"b": 1, "c": true, "d": "x"},
+		})
+		s := NewStorage()
+		for k, v := range m {
+			assert.That(t, s.Set(k, v, 0)).Nil()
+		}
+		v, err := s.Unflatten()
+		assert.That(t, err).Nil()
+		assert.That(t, v).Equal(map[string]any{
+			"a": map[string]any{"b": 1, "c": true, "d": "x"},
+		})
+	})
+}
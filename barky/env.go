@@ -0,0 +1,169 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"io"
+	"strings"
+
+	"github.com/go-spring/spring-base/util"
+)
+
+// EnvOptions controls how flattened keys are mapped to environment
+// variable names by Storage.ToEnv. The zero value maps "servers[0].port"
+// to "SERVERS_0_PORT".
+type EnvOptions struct {
+	// Separator is placed between key segments. Defaults to "_".
+	Separator string
+
+	// IndexSeparator is placed before array indices, e.g. "__" for
+	// "SERVERS__0_PORT". Defaults to Separator.
+	IndexSeparator string
+}
+
+// ToEnv maps every stored value to an environment variable, skipping nil
+// values and empty containers. Names are the given prefix, if any,
+// followed by the key segments in upper case, where characters other
+// than ASCII letters, digits and '_' become '_'; e.g. "db.max-idle" with
+// the prefix "APP" becomes "APP_DB_MAX_IDLE". Trailing separators are
+// trimmed from the prefix, so "APP_" gives the same names.
+//
+// It returns an error if two keys map to the same name, or if a name
+// would start with a digit.
+func (s *Storage) ToEnv(prefix string, opts EnvOptions) (map[string]string, error) {
+	sep := opts.Separator
+	if sep == "" {
+		sep = "_"
+	}
+	indexSep := opts.IndexSeparator
+	if indexSep == "" {
+		indexSep = sep
+	}
+
+	prefix = envName(prefix)
+	for {
+		if p, ok := strings.CutSuffix(prefix, sep); ok {
+			prefix = p
+		} else if p, ok = strings.CutSuffix(prefix, indexSep); ok {
+			prefix = p
+		} else {
+			break
+		}
+	}
+
+	env := make(map[string]string)
+	keys := make(map[string]string) // env name -> flattened key
+	for key, v := range s.Walk("") {
+		if v.IsEmpty() {
+			continue
+		}
+		path, err := SplitPath(key)
+		if err != nil {
+			return nil, err
+		}
+
+		var sb strings.Builder
+		sb.WriteString(prefix)
+		for _, p := range path {
+			if sb.Len() > 0 {
+				if p.Type == PathTypeIndex {
+					sb.WriteString(indexSep)
+				} else {
+					sb.WriteString(sep)
+				}
+			}
+			sb.WriteString(envName(p.Elem))
+		}
+		name := sb.String()
+
+		if c := name[0]; c >= '0' && c <= '9' {
+			return nil, util.FormatError(nil, "invalid env name %s for key %s: starts with a digit", name, key)
+		}
+		if other, ok := keys[name]; ok {
+			return nil, util.FormatError(nil, "env name %s collides: keys %s and %s", name, other, key)
+		}
+		keys[name] = key
+		env[name] = v.Value
+	}
+	return env, nil
+}
+
+// envName converts s to upper case and replaces the characters that
+// are not allowed in environment variable names with '_'.
+func envName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// WriteDotenv writes environment variables in dotenv format, one
+// NAME=value line per variable, sorted by name. Values that contain
+// anything other than letters, digits and the characters "_-.,:/@%+"
+// are double-quoted, with '\\', '"', '$' and '`' escaped by a backslash
+// and newlines, carriage returns and tabs written as "\n", "\r" and "\t".
+func WriteDotenv(w io.Writer, env map[string]string) error {
+	var sb strings.Builder
+	for _, name := range util.OrderedMapKeys(env) {
+		sb.WriteString(name)
+		sb.WriteByte('=')
+		sb.WriteString(quoteDotenv(env[name]))
+		sb.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// quoteDotenv quotes a dotenv value if needed.
+func quoteDotenv(s string) string {
+	safe := true
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_-.,:/@%+", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '\\', '"', '$', '`':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-spring/spring-base/testing/assert"
+)
+
+func TestToEnv(t *testing.T) {
+	s := NewStorage()
+	for key, val := range map[string]any{
+		"db.host":         "localhost",
+		"db.max-idle":     10,
+		"servers[0].port": 8080,
+		"servers[1].port": 8081,
+		"debug":           true,
+		"nil":             Nil,
+		"tags":            EmptySlice,
+	} {
+		assert.That(t, s.Set(key, val, 0)).Nil()
+	}
+
+	t.Run("default", func(t *testing.T) {
+		env, err := s.ToEnv("", EnvOptions{})
+		assert.That(t, err).Nil()
+		assert.That(t, env).Equal(map[string]string{
+			"DB_HOST":        "localhost",
+			"DB_MAX_IDLE":    "10",
+			"SERVERS_0_PORT": "8080",
+			"SERVERS_1_PORT": "8081",
+			"DEBUG":          "true",
+		})
+	})
+
+	t.Run("options", func(t *testing.T) {
+		env, err := s.ToEnv("my-app", EnvOptions{Separator: "__", IndexSeparator: "_"})
+		assert.That(t, err).Nil()
+		assert.That(t, env).Equal(map[string]string{
+			"MY_APP__DB__HOST":        "localhost",
+			"MY_APP__DB__MAX_IDLE":    "10",
+			"MY_APP__SERVERS_0__PORT": "8080",
+			"MY_APP__SERVERS_1__PORT": "8081",
+			"MY_APP__DEBUG":           "true",
+		})
+	})
+
+	t.Run("prefix separator", func(t *testing.T) {
+		for _, prefix := range []string{"APP", "APP_", "app__", "app-"} {
+			env, err := s.ToEnv(prefix, EnvOptions{})
+			assert.That(t, err).Nil()
+			assert.That(t, env["APP_DB_HOST"]).Equal("localhost")
+			assert.That(t, len(env)).Equal(5)
+		}
+		env, err := s.ToEnv("MY_APP__", EnvOptions{Separator: "__", IndexSeparator: "_"})
+		assert.That(t, err).Nil()
+		assert.That(t, env["MY_APP__DB__HOST"]).Equal("localhost")
+	})
+
+	t.Run("collision", func(t *testing.T) {
+		s := NewStorage()
+		assert.That(t, s.Set("db.max-idle", "1", 0)).Nil()
+		assert.That(t, s.Set("db.max_idle", "2", 0)).Nil()
+		_, err := s.ToEnv("", EnvOptions{})
+		assert.Error(t, err).Matches("env name DB_MAX_IDLE collides: keys db.max-idle and db.max_idle")
+
+		s = NewStorage()
+		assert.That(t, s.Set("a.b_c", "1", 0)).Nil()
+		assert.That(t, s.Set("a_b.c", "2", 0)).Nil()
+		_, err = s.ToEnv("", EnvOptions{})
+		assert.Error(t, err).Matches("env name A_B_C collides")
+	})
+
+	t.Run("digit", func(t *testing.T) {
+		s := NewStorage()
+		assert.That(t, s.Set("[0].name", "x", 0)).Nil()
+		_, err := s.ToEnv("", EnvOptions{})
+		assert.Error(t, err).Matches(`invalid env name 0_NAME for key \[0\].name: starts with a digit`)
+
+		env, err := s.ToEnv("app", EnvOptions{})
+		assert.That(t, err).Nil()
+		assert.That(t, env).Equal(map[string]string{"APP_0_NAME": "x"})
+	})
+}
+
+func TestWriteDotenv(t *testing.T) {
+	var sb strings.Builder
+	err := WriteDotenv(&sb, map[string]string{
+		"PLAIN":   "localhost:8080/path",
+		"EMPTY":   "",
+		"SPACE":   "hello world",
+		"QUOTES":  `say "hi"`,
+		"DOLLAR":  "$HOME\\bin",
+		"NEWLINE": "a\nb\tc\r",
+		"UNICODE": "中文",
+		"HASH":    "a#b",
+	})
+	assert.That(t, err).Nil()
+	assert.String(t, sb.String()).Equal(`DOLLAR="\$HOME\\bin"
+EMPTY=
+HASH="a#b"
+NEWLINE="a\nb\tc\r"
+PLAIN=localhost:8080/path
+QUOTES="say \"hi\""
+SPACE="hello world"
+UNICODE="中文"
+`)
+}
@@ -0,0 +1,215 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidPath is matched by errors.Is for every *PathSyntaxError.
+var ErrInvalidPath = errors.New("invalid path")
+
+// ErrConflict is matched by errors.Is for every *ConflictError.
+var ErrConflict = errors.New("property conflict")
+
+// PathSyntaxError reports a malformed hierarchical key.
+type PathSyntaxError struct {
+	Key    string // The key being parsed.
+	Pos    int    // Byte offset in Key where the problem was detected.
+	Reason string // Description of the problem.
+}
+
+// Error implements the error interface.
+func (e *PathSyntaxError) Error() string {
+	if e.Key == "" {
+		return "invalid key: " + e.Reason
+	}
+	return fmt.Sprintf("invalid key %q at pos %d: %s", e.Key, e.Pos, e.Reason)
+}
+
+// Is reports whether target is ErrInvalidPath.
+func (e *PathSyntaxError) Is(target error) bool {
+	return target == ErrInvalidPath
+}
+
+// Structure names used by ConflictError.
+const (
+	StructValue     = "value"     // A leaf value, including nil and empty containers.
+	StructMap       = "map"       // A map with child keys.
+	StructArray     = "array"     // An array with child indices.
+	StructContainer = "container" // Either a map or an array.
+)
+
+// ConflictError reports a structural conflict, such as setting a value
+// where a map already exists, or indexing into a map as if it were
+// an array. A value defined by more than one file is not a conflict,
+// even in batch mode (see Storage.SetAll): the later file overrides it,
+// and Loader.Overrides reports it.
+type ConflictError struct {
+	Path          string // The path where the conflict was detected.
+	Existing      string // The structure already stored, e.g. StructValue.
+	Attempted     string // The structure that was requested, e.g. StructMap.
+	ExistingFile  string // The file that defined the existing structure, if known.
+	AttemptedFile string // The file that requested the new structure, if known.
+}
+
+// Error implements the error interface.
+func (e *ConflictError) Error() string {
+	msg := fmt.Sprintf("property conflict at path %s: existing %s", e.Path, e.Existing)
+	if e.ExistingFile != "" {
+		msg += fmt.Sprintf(" (from %s)", e.ExistingFile)
+	}
+	msg += " vs " + e.Attempted
+	if e.AttemptedFile != "" {
+		msg += fmt.Sprintf(" (from %s)", e.AttemptedFile)
+	}
+	return msg
+}
+
+// Is reports whether target is ErrConflict.
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// TransformError reports a value rejected by a Transformer.
+type TransformError struct {
+	Path        string // The key of the rejected value.
+	Transformer string // The name of the transformer.
+	Err         error  // The error returned by the transformer.
+}
+
+// Error implements the error interface.
+func (e *TransformError) Error() string {
+	return fmt.Sprintf("invalid value at path %s (transformer %s): %v", e.Path, e.Transformer, e.Err)
+}
+
+// Unwrap returns the error returned by the transformer.
+func (e *TransformError) Unwrap() error {
+	return e.Err
+}
+
+// PatchError reports the operation of a JSON Patch that could not be
+// applied, see Storage.ApplyPatch.
+type PatchError struct {
+	Patch string // The name the patch was applied under.
+	Index int    // The index of the operation in the patch.
+	Op    string // The operation, e.g. "add".
+	Path  string // The JSON Pointer of the operation.
+	Err   error  // The cause.
+}
+
+// Error implements the error interface.
+func (e *PatchError) Error() string {
+	return fmt.Sprintf("apply patch %s: operation %d (%s %s): %v", e.Patch, e.Index, e.Op, e.Path, e.Err)
+}
+
+// Unwrap returns the cause.
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// AliasConflictError reports a value stored under a deprecated key
+// whose replacement holds a different value, see Storage.CheckAliases.
+// It matches ErrConflict.
+type AliasConflictError struct {
+	Old      string // The deprecated key.
+	New      string // The replacement key.
+	OldValue string // The value under Old.
+	NewValue string // The value under New, or "<map>" or "<array>".
+	OldFile  string // The file that set Old, if known.
+	NewFile  string // The file that set New, if known.
+}
+
+// Error implements the error interface.
+func (e *AliasConflictError) Error() string {
+	return fmt.Sprintf("alias conflict: %s = %q (from %s) vs %s = %q (from %s)",
+		e.Old, e.OldValue, e.OldFile, e.New, e.NewValue, e.NewFile)
+}
+
+// Is reports whether target is ErrConflict.
+func (e *AliasConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// ConflictErrors aggregates the conflicts found by a batch operation,
+// in the order they were detected. errors.Is(err, ErrConflict) and
+// errors.As with a *ConflictError work on the aggregate as well.
+type ConflictErrors []*ConflictError
+
+// Error implements the error interface.
+func (e ConflictErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d property conflicts:", len(e))
+	for _, err := range e {
+		sb.WriteString("\n\t")
+		sb.WriteString(err.Error())
+	}
+	return sb.String()
+}
+
+// Unwrap returns the individual conflicts.
+func (e ConflictErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// structOf returns the structure name of a path element type.
+func structOf(typ PathType) string {
+	if typ == PathTypeIndex {
+		return StructArray
+	}
+	return StructMap
+}
+
+// conflictError creates a ConflictError at path, where the existing
+// structure is stored under existingKey.
+func (s *Storage) conflictError(path, existingKey, existing, attempted string) *ConflictError {
+	return &ConflictError{
+		Path:         path,
+		Existing:     existing,
+		Attempted:    attempted,
+		ExistingFile: s.sourceOf(existingKey),
+	}
+}
+
+// sourceOf returns the name of the file that defined the first value
+// under the given key, or "" if it is unknown.
+func (s *Storage) sourceOf(key string) string {
+	for _, v := range s.Walk(key) {
+		return s.fileName(v.File)
+	}
+	return ""
+}
+
+// fileName returns the name of the file with the given index,
+// or "" if no such file is registered.
+func (s *Storage) fileName(idx int8) string {
+	for name, i := range s.file {
+		if i == idx {
+			return name
+		}
+	}
+	return ""
+}
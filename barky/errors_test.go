@@ -0,0 +1,208 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-spring/spring-base/testing/assert"
+)
+
+func TestPathSyntaxError(t *testing.T) {
+
+	t.Run("split path", func(t *testing.T) {
+		tests := []struct {
+			key    string
+			pos    int
+			reason string
+		}{
+			{"", 0, "empty string"},
+			{"a b", 1, "contains space"},
+			{"a..b", 2, "empty key between dots"},
+			{"a[x]", 2, `index must be an unsigned integer (got "x")`},
+			{"a[0]b", 4, `unexpected character 'b' after ']'`},
+			{"a.b[", 3, "unclosed '['"},
+		}
+		for _, tt := range tests {
+			_, err := SplitPath(tt.key)
+			var e *PathSyntaxError
+			assert.That(t, errors.As(err, &e)).True()
+			assert.That(t, e).Equal(&PathSyntaxError{Key: tt.key, Pos: tt.pos, Reason: tt.reason})
+			assert.That(t, errors.Is(err, ErrInvalidPath)).True()
+			assert.That(t, errors.Is(err, ErrConflict)).False()
+		}
+	})
+
+	t.Run("flattener split path", func(t *testing.T) {
+		f := NewFlattener()
+		f.Separator = "__"
+		tests := []struct {
+			key    string
+			pos    int
+			reason string
+		}{
+			{"a____b", 3, "empty key segment"},
+			{"a__b]", 4, "']' without matching '['"},
+			{"a__b[0]x", 7, `unexpected character 'x' after ']'`},
+			{"a__b[0][1", 7, "unclosed '['"},
+			{"a__b[x]", 5, `index must be an unsigned integer (got "x")`},
+		}
+		for _, tt := range tests {
+			_, err := f.SplitPath(tt.key)
+			var e *PathSyntaxError
+			assert.That(t, errors.As(err, &e)).True()
+			assert.That(t, e).Equal(&PathSyntaxError{Key: tt.key, Pos: tt.pos, Reason: tt.reason})
+		}
+	})
+
+	t.Run("storage", func(t *testing.T) {
+		s := NewStorage()
+		err := s.Set("", "a", 0)
+		assert.That(t, errors.Is(err, ErrInvalidPath)).True()
+		err = s.Set("a..b", "a", 0)
+		assert.That(t, errors.Is(err, ErrInvalidPath)).True()
+		_, err = s.SubKeys("a[")
+		assert.That(t, errors.Is(err, ErrInvalidPath)).True()
+	})
+}
+
+func TestConflictError(t *testing.T) {
+	s := NewStorage()
+	base := s.AddFile("base.yaml")
+	override := s.AddFile("override.yaml")
+	assert.That(t, s.Set("a.b", "1", base)).Nil()
+	assert.That(t, s.Set("list[0]", "x", base)).Nil()
+	assert.That(t, s.Set("list[1].name", "y", override)).Nil()
+
+	tests := []struct {
+		key   string
+		value any
+		want  ConflictError
+		msg   string
+	}{
+		{
+			key:  "a.b.c",
+			want: ConflictError{Path: "a.b.c", Existing: StructValue, Attempted: StructMap, ExistingFile: "base.yaml"},
+			msg:  `property conflict at path a.b.c: existing value \(from base.yaml\) vs map`,
+		},
+		{
+			key:  "a",
+			want: ConflictError{Path: "a", Existing: StructMap, Attempted: StructValue, ExistingFile: "base.yaml"},
+			msg:  `property conflict at path a: existing map \(from base.yaml\) vs value`,
+		},
+		{
+			key:  "list.name",
+			want: ConflictError{Path: "list.name", Existing: StructArray, Attempted: StructMap, ExistingFile: "base.yaml"},
+			msg:  `property conflict at path list.name: existing array \(from base.yaml\) vs map`,
+		},
+		{
+			key:  "list[1][0]",
+			want: ConflictError{Path: "list[1][0]", Existing: StructMap, Attempted: StructArray, ExistingFile: "override.yaml"},
+			msg:  `property conflict at path list\[1\]\[0\]: existing map \(from override.yaml\) vs array`,
+		},
+		{
+			key:  "[0]",
+			want: ConflictError{Path: "[0]", Existing: StructMap, Attempted: StructArray, ExistingFile: "base.yaml"},
+			msg:  `property conflict at path \[0\]: existing map \(from base.yaml\) vs array`,
+		},
+	}
+	for _, tt := range tests {
+		err := s.Set(tt.key, "v", override)
+		assert.Error(t, err).Matches(tt.msg)
+		var e *ConflictError
+		assert.That(t, errors.As(err, &e)).True()
+		assert.That(t, *e).Equal(tt.want)
+		assert.That(t, errors.Is(err, ErrConflict)).True()
+		assert.That(t, errors.Is(err, ErrInvalidPath)).False()
+	}
+
+	t.Run("sub keys", func(t *testing.T) {
+		_, err := s.SubKeys("a.b")
+		var e *ConflictError
+		assert.That(t, errors.As(err, &e)).True()
+		assert.That(t, *e).Equal(ConflictError{
+			Path: "a.b", Existing: StructValue, Attempted: StructContainer, ExistingFile: "base.yaml",
+		})
+
+		_, err = s.SubKeys("list.x")
+		assert.That(t, errors.As(err, &e)).True()
+		assert.That(t, e.Existing).Equal(StructArray)
+	})
+
+	t.Run("unknown file", func(t *testing.T) {
+		s := NewStorage()
+		assert.That(t, s.Set("a", "1", 5)).Nil()
+		err := s.Set("a.b", "1", 5)
+		assert.Error(t, err).Matches("^property conflict at path a.b: existing value vs map$")
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		s := NewStorage()
+		base := s.AddFile("base.json")
+		assert.That(t, s.SetAll(map[string]any{
+			"db.host": "localhost",
+			"db.port": 5432,
+			"list[0]": "a",
+		}, base)).Nil()
+
+		app := s.AddFile("app.json")
+		err := s.SetAll(map[string]any{
+			"db.host.name": "x",   // value vs map
+			"list.a":       "b",   // array vs map
+			"db.port":      "1",   // overrides base.json
+			"log":          "x",   // valid
+			"log.level":    "dbg", // conflicts within the batch
+			"name":         "app", // valid
+		}, app)
+
+		var conflicts ConflictErrors
+		assert.That(t, errors.As(err, &conflicts)).True()
+		assert.That(t, conflicts).Equal(ConflictErrors{
+			{Path: "db.host.name", Existing: StructValue, Attempted: StructMap, ExistingFile: "base.json", AttemptedFile: "app.json"},
+			{Path: "list.a", Existing: StructArray, Attempted: StructMap, ExistingFile: "base.json", AttemptedFile: "app.json"},
+			{Path: "log.level", Existing: StructValue, Attempted: StructMap, ExistingFile: "app.json", AttemptedFile: "app.json"},
+		})
+		assert.That(t, errors.Is(err, ErrConflict)).True()
+		var e *ConflictError
+		assert.That(t, errors.As(err, &e)).True()
+		assert.That(t, e.Path).Equal("db.host.name")
+		assert.Error(t, err).Matches(`^3 property conflicts:
+	property conflict at path db.host.name: existing value \(from base.json\) vs map \(from app.json\)
+	property conflict at path list.a: existing array \(from base.json\) vs map \(from app.json\)
+`)
+
+		assert.That(t, s.Data()).Equal(map[string]string{
+			"db.host": "localhost",
+			"db.port": "1",
+			"list[0]": "a",
+			"log":     "x",
+			"name":    "app",
+		})
+
+		// Values from the same file may be updated.
+		assert.That(t, s.SetAll(map[string]any{"name": "app2"}, app)).Nil()
+		assert.That(t, s.Get("name")).Equal("app2")
+
+		err = s.SetAll(map[string]any{"a": "1", "b[": "2"}, app)
+		assert.That(t, errors.Is(err, ErrInvalidPath)).True()
+
+		err = s.SetAll(map[string]any{"db.port": "2"}, base)
+		assert.That(t, err).Nil()
+		assert.That(t, s.Get("db.port")).Equal("2")
+	})
+}
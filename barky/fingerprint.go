@@ -0,0 +1,62 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// Fingerprint returns a 64-bit FNV-1a hash of the keys, kinds and values
+// under prefix, e.g. to detect whether a reload changed the part of the
+// configuration a worker depends on. The empty prefix covers the whole
+// Storage. The hash is stable across processes and independent of the
+// order in which values were set, and files are not taken into account,
+// so the same content loaded from other files has the same fingerprint.
+// Keys are hashed relative to prefix, so that equal subtrees have equal
+// fingerprints wherever they are. An absent prefix hashes like an empty
+// subtree.
+func (s *Storage) Fingerprint(prefix string) uint64 {
+	return s.FingerprintFunc(prefix, nil)
+}
+
+// FingerprintFunc is like Fingerprint, but only hashes the values whose
+// flattened key satisfies keep, e.g. Pattern.Match. A nil keep hashes
+// every value.
+func (s *Storage) FingerprintFunc(prefix string, keep func(key string) bool) uint64 {
+	h := fnv.New64a()
+	var buf []byte
+	for key, v := range s.Walk(prefix) {
+		if keep != nil && !keep(key) {
+			continue
+		}
+		rel := key[len(prefix):]
+		buf = binary.AppendUvarint(buf[:0], uint64(len(rel)))
+		buf = append(buf, rel...)
+		buf = append(buf, byte(v.Kind))
+		buf = binary.AppendUvarint(buf, uint64(len(v.Value)))
+		buf = append(buf, v.Value...)
+		_, _ = h.Write(buf)
+	}
+	return h.Sum64()
+}
+
+// Fingerprint returns the fingerprint of the values under the view's
+// prefix, see Storage.Fingerprint.
+func (v *View) Fingerprint() uint64 {
+	return v.s.Fingerprint(v.prefix)
+}
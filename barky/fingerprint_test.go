@@ -0,0 +1,103 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-spring/spring-base/testing/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	data := map[string]any{
+		"db.primary.host":  "a",
+		"db.primary.port":  5432,
+		"db.replica.host":  "a",
+		"db.replica.port":  5432,
+		"log.level":        "info",
+		"log.outputs":      EmptySlice,
+		"servers[0].name":  "s0",
+		"servers[1].name":  "s1",
+		"servers[1].debug": true,
+	}
+	newStorage := func(reverse bool) *Storage {
+		s := NewStorage()
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		if reverse {
+			for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+				keys[i], keys[j] = keys[j], keys[i]
+			}
+		}
+		file := s.AddFile(fmt.Sprint(reverse))
+		for _, k := range keys {
+			assert.That(t, s.Set(k, data[k], file)).Nil()
+		}
+		return s
+	}
+
+	s, r := newStorage(false), newStorage(true)
+	assert.That(t, s.Fingerprint("")).Equal(r.Fingerprint(""))
+	builder := NewBuilder()
+	assert.That(t, builder.SetAll(data, 0)).Nil()
+	assert.That(t, builder.Build().Fingerprint("db")).Equal(s.Fingerprint("db"))
+	assert.That(t, s.Fingerprint("db.primary")).Equal(s.Fingerprint("db.replica"))
+	assert.That(t, s.Sub("db").Sub("primary").Fingerprint()).Equal(s.Fingerprint("db.primary"))
+	assert.That(t, s.Fingerprint("db")).Equal(r.Fingerprint("db"))
+	assert.That(t, s.Fingerprint("none")).Equal(NewStorage().Fingerprint(""))
+
+	// A change is only visible in the subtrees that contain it.
+	before := map[string]uint64{}
+	for _, prefix := range []string{"", "db", "log", "servers", "servers[1]"} {
+		before[prefix] = s.Fingerprint(prefix)
+	}
+	assert.That(t, s.Set("servers[1].debug", "true", 0)).Nil()
+	assert.That(t, s.Fingerprint("")).NotEqual(before[""])
+	assert.That(t, s.Fingerprint("servers")).NotEqual(before["servers"])
+	assert.That(t, s.Fingerprint("servers[1]")).NotEqual(before["servers[1]"])
+	assert.That(t, s.Fingerprint("db")).Equal(before["db"])
+	assert.That(t, s.Fingerprint("log")).Equal(before["log"])
+
+	// Keys and values don't run into each other.
+	a, b := NewStorage(), NewStorage()
+	assert.That(t, a.Set("ab", "c", 0)).Nil()
+	assert.That(t, b.Set("a", "bc", 0)).Nil()
+	assert.That(t, a.Fingerprint("")).NotEqual(b.Fingerprint(""))
+
+	// Filtered keys are ignored.
+	p := MustCompilePattern("servers[*].name")
+	assert.That(t, s.FingerprintFunc("", p.Match)).Equal(r.FingerprintFunc("", p.Match))
+	assert.That(t, s.FingerprintFunc("servers", p.Match)).NotEqual(s.Fingerprint("servers"))
+
+	// The hash is stable across processes.
+	assert.That(t, NewStorage().Fingerprint("")).Equal(uint64(0xcbf29ce484222325))
+	assert.That(t, b.Fingerprint("")).Equal(uint64(0xaec684416c611ee2))
+}
+
+func BenchmarkFingerprint(b *testing.B) {
+	s := NewStorage()
+	for i := range 1000 {
+		_ = s.Set(fmt.Sprintf("servers[%d].host", i), "host", 0)
+		_ = s.Set(fmt.Sprintf("servers[%d].port", i), i, 0)
+	}
+	for b.Loop() {
+		s.Fingerprint("")
+	}
+}
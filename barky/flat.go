@@ -17,70 +17,277 @@
 package barky
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 
+	"github.com/go-spring/spring-base/util"
 	"github.com/spf13/cast"
 )
 
+// Marker is a typed placeholder for a flattened value that has no
+// scalar form: a nil value or an empty container. Markers are never
+// confused with user strings such as "[]" or "<nil>".
+type Marker int8
+
+const (
+	Nil        Marker = iota + 1 // A nil value.
+	EmptyMap                     // An empty map.
+	EmptySlice                   // An empty slice or array.
+)
+
+// String returns the conventional textual form of the marker.
+func (m Marker) String() string {
+	switch m {
+	case Nil:
+		return "<nil>"
+	case EmptyMap:
+		return "{}"
+	case EmptySlice:
+		return "[]"
+	default:
+		return "Marker(" + strconv.Itoa(int(m)) + ")"
+	}
+}
+
+// IndexStyle determines how array indices are written in flattened keys.
+type IndexStyle int8
+
+const (
+	IndexBracket IndexStyle = iota // Indices in brackets, e.g. "a[0]".
+	IndexSegment                   // Indices as plain segments, e.g. "a.0".
+)
+
+// Flattener holds the conventions used to flatten nested values into
+// flat keys, and to split and join those keys again. The zero value is
+// not ready for use; start from NewFlattener and adjust its fields.
+type Flattener struct {
+	// Separator is placed between map keys, e.g. "." or "__".
+	Separator string
+
+	// IndexStyle determines how array indices are written. With
+	// IndexSegment, indices are separated like map keys, so a map key
+	// consisting only of digits is parsed back as an index.
+	IndexStyle IndexStyle
+
+	// KeepTypes keeps booleans and numbers (including json.Number) as
+	// they are instead of converting them to strings, so that Storage
+	// can preserve their types.
+	KeepTypes bool
+
+	// Values used for nil values, empty maps and empty slices/arrays.
+	// They default to the Nil, EmptyMap and EmptySlice markers, and may
+	// be set to a string such as "" to produce real empty strings.
+	NilValue        any
+	EmptyMapValue   any
+	EmptySliceValue any
+}
+
+// defaultFlattener is used by the package-level flatten functions.
+var defaultFlattener = NewFlattener()
+
+// NewFlattener returns a Flattener with the default conventions:
+// "." as separator, "[i]" for indices, and the Nil, EmptyMap and
+// EmptySlice markers for nil values, empty maps and empty slices/arrays.
+func NewFlattener() *Flattener {
+	return &Flattener{
+		Separator:       ".",
+		IndexStyle:      IndexBracket,
+		NilValue:        Nil,
+		EmptyMapValue:   EmptyMap,
+		EmptySliceValue: EmptySlice,
+	}
+}
+
 // FlattenMap takes a nested map[string]any and flattens it into a
-// map[string]string. Nested maps are represented using dot-notation
-// (e.g. "parent.child"), and slices/arrays are represented using index-notation
-// (e.g. "array[0]"). The following rules apply:
-//   - Nil values (both untyped and typed nil) are represented as "<nil>".
-//   - Nil elements in slices/arrays are preserved and represented as "<nil>".
-//   - Empty maps are represented as "{}".
-//   - Empty slices/arrays are represented as "[]".
+// map[string]any whose values are strings or Markers. Nested maps are
+// represented using dot-notation (e.g. "parent.child"), and slices/arrays
+// are represented using index-notation (e.g. "array[0]"). The following
+// rules apply:
+//   - Nil values (both untyped and typed nil) are represented as Nil.
+//   - Nil elements in slices/arrays are preserved and represented as Nil.
+//   - Empty maps are represented as EmptyMap.
+//   - Empty slices/arrays are represented as EmptySlice.
 //   - All primitive values are converted to strings using cast.ToString.
-func FlattenMap(m map[string]any) map[string]string {
-	result := make(map[string]string)
-	for key, val := range m {
-		FlattenValue(key, val, result)
-	}
-	return result
+//
+// Use a Flattener with KeepTypes to preserve booleans and numbers.
+func FlattenMap(m map[string]any) map[string]any {
+	return defaultFlattener.FlattenMap(m)
 }
 
 // FlattenValue recursively flattens a value (map, slice, array, or primitive)
 // into the result map under the given key. Nested structures are expanded
 // using dot notation (for maps) and index notation (for slices/arrays).
-func FlattenValue(key string, val any, result map[string]string) {
+func FlattenValue(key string, val any, result map[string]any) {
+	defaultFlattener.FlattenValue(key, val, result)
+}
+
+// FlattenMap is like the package-level FlattenMap, but follows the
+// conventions of f.
+func (f *Flattener) FlattenMap(m map[string]any) map[string]any {
+	result := make(map[string]any)
+	for key, val := range m {
+		f.FlattenValue(key, val, result)
+	}
+	return result
+}
+
+// FlattenValue is like the package-level FlattenValue, but follows the
+// conventions of f.
+func (f *Flattener) FlattenValue(key string, val any, result map[string]any) {
 	if val == nil { // untyped nil
-		result[key] = "<nil>"
+		result[key] = f.NilValue
 		return
 	}
 	switch v := reflect.ValueOf(val); v.Kind() {
 	case reflect.Map:
 		if v.IsNil() { // typed nil map
-			result[key] = "<nil>"
+			result[key] = f.NilValue
 			return
 		}
 		if v.Len() == 0 { // empty map
-			result[key] = "{}"
+			result[key] = f.EmptyMapValue
 			return
 		}
 		iter := v.MapRange()
 		for iter.Next() {
 			mapKey := cast.ToString(iter.Key().Interface())
 			mapValue := iter.Value().Interface()
-			FlattenValue(key+"."+mapKey, mapValue, result)
+			f.FlattenValue(f.joinKey(key, mapKey), mapValue, result)
 		}
 	case reflect.Slice:
 		if v.IsNil() { // typed nil slice
-			result[key] = "<nil>"
+			result[key] = f.NilValue
 			return
 		}
 		fallthrough
 	case reflect.Array:
 		if v.Len() == 0 { // empty slice/array
-			result[key] = "[]"
+			result[key] = f.EmptySliceValue
 			return
 		}
 		for i := range v.Len() {
-			subKey := fmt.Sprintf("%s[%d]", key, i)
+			subKey := f.joinIndex(key, strconv.Itoa(i))
 			subValue := v.Index(i).Interface()
-			FlattenValue(subKey, subValue, result)
+			f.FlattenValue(subKey, subValue, result)
 		}
 	default:
-		result[key] = cast.ToString(val)
+		result[key] = f.scalar(val)
+	}
+}
+
+// scalar converts a scalar value for the flattened output.
+func (f *Flattener) scalar(val any) any {
+	if f.KeepTypes {
+		switch val.(type) {
+		case bool, int, int8, int16, int32, int64,
+			uint, uint8, uint16, uint32, uint64,
+			float32, float64, json.Number:
+			return val
+		}
+	}
+	return cast.ToString(val)
+}
+
+// joinKey appends a map key to a flattened key.
+func (f *Flattener) joinKey(key, elem string) string {
+	if key == "" {
+		return elem
+	}
+	return key + f.Separator + elem
+}
+
+// joinIndex appends an array index to a flattened key.
+func (f *Flattener) joinIndex(key, index string) string {
+	if f.IndexStyle == IndexBracket {
+		return key + "[" + index + "]"
+	}
+	return f.joinKey(key, index)
+}
+
+// JoinPath is like the package-level JoinPath, but follows the
+// conventions of f.
+func (f *Flattener) JoinPath(path []Path) string {
+	var key string
+	for _, p := range path {
+		switch p.Type {
+		case PathTypeKey:
+			key = f.joinKey(key, p.Elem)
+		case PathTypeIndex:
+			key = f.joinIndex(key, p.Elem)
+		}
+	}
+	return key
+}
+
+// SplitPath is like the package-level SplitPath, but follows the
+// conventions of f, so that f.SplitPath(f.JoinPath(path)) returns path.
+// Key segments may contain any character except the separator and,
+// with IndexBracket, square brackets.
+func (f *Flattener) SplitPath(key string) (_ []Path, err error) {
+	if f.Separator == "." && f.IndexStyle == IndexBracket {
+		return SplitPath(key)
+	}
+	if key == "" {
+		return nil, &PathSyntaxError{Key: key, Reason: "empty string"}
+	}
+	if f.Separator == "" {
+		return nil, util.FormatError(nil, "invalid flattener: empty separator")
+	}
+
+	var (
+		path []Path
+		pos  int // start offset of the current segment
+	)
+	for i, s := range strings.Split(key, f.Separator) {
+		if i > 0 {
+			pos += len(f.Separator)
+		}
+		start := pos
+		pos += len(s)
+
+		if f.IndexStyle == IndexSegment {
+			if s != "" && strings.Trim(s, "0123456789") == "" {
+				path, err = appendIndex(path, s)
+			} else {
+				path, err = appendKey(path, s)
+			}
+			if err != nil {
+				return nil, &PathSyntaxError{Key: key, Pos: start, Reason: err.Error()}
+			}
+			continue
+		}
+
+		// IndexBracket: an optional key followed by zero or more "[i]".
+		name, rest := s, ""
+		if j := strings.IndexByte(s, '['); j >= 0 {
+			name, rest = s[:j], s[j:]
+		}
+		// Only the first segment may start with an index, e.g. "[0]__a".
+		if name != "" || i > 0 || rest == "" {
+			if j := strings.IndexByte(name, ']'); j >= 0 {
+				return nil, &PathSyntaxError{Key: key, Pos: start + j, Reason: "']' without matching '['"}
+			}
+			if path, err = appendKey(path, name); err != nil {
+				return nil, &PathSyntaxError{Key: key, Pos: start, Reason: err.Error()}
+			}
+		}
+		for rest != "" {
+			at := pos - len(rest)
+			if rest[0] != '[' {
+				return nil, &PathSyntaxError{Key: key, Pos: at, Reason: fmt.Sprintf("unexpected character %q after ']'", rest[0])}
+			}
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, &PathSyntaxError{Key: key, Pos: at, Reason: "unclosed '['"}
+			}
+			if path, err = appendIndex(path, rest[1:end]); err != nil {
+				return nil, &PathSyntaxError{Key: key, Pos: at + 1, Reason: err.Error()}
+			}
+			rest = rest[end+1:]
+		}
 	}
+	return path, nil
 }
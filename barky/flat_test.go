@@ -26,7 +26,7 @@ func TestFlatten(t *testing.T) {
 	tests := []struct {
 		name     string
 		input    map[string]any
-		expected map[string]string
+		expected map[string]any
 	}{
 		{
 			name: "basic types",
@@ -34,7 +34,7 @@ func TestFlatten(t *testing.T) {
 				"int": 123,
 				"str": "abc",
 			},
-			expected: map[string]string{
+			expected: map[string]any{
 				"int": "123",
 				"str": "abc",
 			},
@@ -74,30 +74,30 @@ func TestFlatten(t *testing.T) {
 				"empty_arr": []any{},
 				"empty_map": map[string]string{},
 			},
-			expected: map[string]string{
-				"nil":           "<nil>",
-				"nil_arr":       "<nil>",
-				"nil_map":       "<nil>",
-				"empty_arr":     "[]",
-				"empty_map":     "{}",
+			expected: map[string]any{
+				"nil":           Nil,
+				"nil_arr":       Nil,
+				"nil_map":       Nil,
+				"empty_arr":     EmptySlice,
+				"empty_map":     EmptyMap,
 				"map.a":         "123",
 				"map.b":         "456",
 				"map.arr[0]":    "abc",
 				"map.arr[1]":    "def",
-				"map.empty_arr": "[]",
-				"map.empty_map": "{}",
-				"map.nil":       "<nil>",
-				"map.nil_arr":   "<nil>",
-				"map.nil_map":   "<nil>",
+				"map.empty_arr": EmptySlice,
+				"map.empty_map": EmptyMap,
+				"map.nil":       Nil,
+				"map.nil_arr":   Nil,
+				"map.nil_map":   Nil,
 				"arr[0]":        "abc",
 				"arr[1]":        "def",
 				"arr[2].a":      "123",
 				"arr[2].b":      "456",
-				"arr[3]":        "<nil>",
-				"arr[4]":        "<nil>",
-				"arr[5]":        "<nil>",
-				"arr[6]":        "[]",
-				"arr[7]":        "{}",
+				"arr[3]":        Nil,
+				"arr[4]":        Nil,
+				"arr[5]":        Nil,
+				"arr[6]":        EmptySlice,
+				"arr[7]":        EmptyMap,
 			},
 		},
 		{
@@ -109,7 +109,7 @@ func TestFlatten(t *testing.T) {
 				"string":  "text",
 				"complex": 1 + 2i, // This type is not supported by cast.ToString
 			},
-			expected: map[string]string{
+			expected: map[string]any{
 				"bool":    "true",
 				"int":     "42",
 				"float":   "3.14",
@@ -128,7 +128,7 @@ func TestFlatten(t *testing.T) {
 					},
 				},
 			},
-			expected: map[string]string{
+			expected: map[string]any{
 				"level1.level2.level3.value": "deep",
 			},
 		},
@@ -138,7 +138,7 @@ func TestFlatten(t *testing.T) {
 				"chan": make(chan int),
 				"func": func() {},
 			},
-			expected: map[string]string{
+			expected: map[string]any{
 				"chan": "",
 				"func": "",
 			},
@@ -148,14 +148,14 @@ func TestFlatten(t *testing.T) {
 			input: map[string]any{
 				"iface": []any{any(nil)},
 			},
-			expected: map[string]string{
-				"iface[0]": "<nil>",
+			expected: map[string]any{
+				"iface[0]": Nil,
 			},
 		},
 		{
 			name:     "empty input",
 			input:    map[string]any{},
-			expected: map[string]string{},
+			expected: map[string]any{},
 		},
 	}
 
@@ -166,3 +166,95 @@ func TestFlatten(t *testing.T) {
 		})
 	}
 }
+
+func TestFlattener(t *testing.T) {
+	input := map[string]any{
+		"a": map[string]any{
+			"b": []any{"x", map[string]any{"c": 1}},
+		},
+		"nil":       nil,
+		"empty_arr": []any{},
+		"empty_map": map[string]any{},
+	}
+
+	t.Run("default", func(t *testing.T) {
+		f := NewFlattener()
+		assert.That(t, f.FlattenMap(input)).Equal(FlattenMap(input))
+	})
+
+	t.Run("underscore separator", func(t *testing.T) {
+		f := NewFlattener()
+		f.Separator = "__"
+		f.IndexStyle = IndexSegment
+		f.NilValue = ""
+		f.EmptyMapValue = ""
+		f.EmptySliceValue = ""
+		assert.That(t, f.FlattenMap(input)).Equal(map[string]any{
+			"a__b__0":    "x",
+			"a__b__1__c": "1",
+			"nil":        "",
+			"empty_arr":  "",
+			"empty_map":  "",
+		})
+	})
+
+	t.Run("slash separator with brackets", func(t *testing.T) {
+		f := NewFlattener()
+		f.Separator = "/"
+		assert.That(t, f.FlattenMap(input)).Equal(map[string]any{
+			"a/b[0]":    "x",
+			"a/b[1]/c":  "1",
+			"nil":       Nil,
+			"empty_arr": EmptySlice,
+			"empty_map": EmptyMap,
+		})
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		paths := [][]Path{
+			{{PathTypeKey, "a"}},
+			{{PathTypeKey, "a"}, {PathTypeIndex, "0"}, {PathTypeKey, "b.c"}},
+			{{PathTypeIndex, "1"}, {PathTypeIndex, "2"}, {PathTypeKey, "x"}},
+		}
+		for _, style := range []IndexStyle{IndexBracket, IndexSegment} {
+			for _, sep := range []string{"__", "/"} {
+				f := NewFlattener()
+				f.Separator = sep
+				f.IndexStyle = style
+				for _, path := range paths {
+					key := f.JoinPath(path)
+					p, err := f.SplitPath(key)
+					assert.That(t, err).Nil()
+					assert.That(t, p).Equal(path)
+				}
+			}
+		}
+
+		f := NewFlattener()
+		f.Separator = "__"
+		f.IndexStyle = IndexSegment
+		assert.That(t, f.JoinPath(paths[1])).Equal("a__0__b.c")
+	})
+
+	t.Run("split errors", func(t *testing.T) {
+		f := NewFlattener()
+		f.Separator = "/"
+		for key, msg := range map[string]string{
+			"":        "invalid key: empty string",
+			"a//b":    "empty key segment",
+			"a/[0]":   "empty key segment",
+			"a[0]b":   `unexpected character 'b' after '\]'`,
+			"a[0":     `unclosed '\['`,
+			"a]":      `'\]' without matching '\['`,
+			"a[x]":    "index must be an unsigned integer",
+			"a/b c/d": "contains space",
+		} {
+			_, err := f.SplitPath(key)
+			assert.Error(t, err).Matches(msg)
+		}
+
+		f.Separator = ""
+		_, err := f.SplitPath("a")
+		assert.Error(t, err).Matches("empty separator")
+	})
+}
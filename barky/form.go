@@ -0,0 +1,81 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/go-spring/spring-base/util"
+)
+
+// SetValues stores form or query values, whose names are flattened keys
+// such as "servers[0].host", with the given file index. A name with a
+// single value is set as is, and a name repeated several times, as in
+// "tags=a&tags=b", becomes an array ("tags[0]", "tags[1]"). Names are
+// applied in lexicographic order, and the first invalid name or
+// structural conflict stops the operation. The values are stored
+// atomically: if an error is returned, the Storage is left unchanged.
+func (s *Storage) SetValues(values url.Values, file int8) error {
+	w := s.workingCopy()
+	if err := w.setValues(values, file); err != nil {
+		return err
+	}
+	s.commit(w)
+	return nil
+}
+
+// setValues is SetValues without the working copy.
+func (s *Storage) setValues(values url.Values, file int8) error {
+	for _, key := range util.OrderedMapKeys(values) {
+		vals := values[key]
+		if _, err := SplitPath(key); err != nil {
+			return err
+		}
+		switch len(vals) {
+		case 0:
+			continue
+		case 1:
+			if err := s.Set(key, vals[0], file); err != nil {
+				return err
+			}
+		default:
+			for i, v := range vals {
+				if err := s.Set(key+"["+strconv.Itoa(i)+"]", v, file); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Values encodes the stored values as url.Values, one value per
+// flattened key, e.g. "servers[0].host". Array elements keep their
+// indices rather than being written as repeated names, so that arrays
+// with a single element survive a round trip through SetValues. Nil
+// values and empty containers are omitted. Use Values().Encode() to
+// build a query string.
+func (s *Storage) Values() url.Values {
+	values := make(url.Values)
+	for key, v := range s.All() {
+		if !v.IsEmpty() {
+			values[key] = []string{v.Value}
+		}
+	}
+	return values
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/go-spring/spring-base/testing/assert"
+)
+
+func TestValues(t *testing.T) {
+
+	t.Run("set values", func(t *testing.T) {
+		values, err := url.ParseQuery("servers[0].host=a&servers[0].port=80&servers[1].host=b&tags=x&tags=y&name=app&none")
+		assert.That(t, err).Nil()
+		values["empty"] = nil
+
+		s := NewStorage()
+		file := s.AddFile("form")
+		assert.That(t, s.SetValues(values, file)).Nil()
+		assert.That(t, s.Data()).Equal(map[string]string{
+			"servers[0].host": "a",
+			"servers[0].port": "80",
+			"servers[1].host": "b",
+			"tags[0]":         "x",
+			"tags[1]":         "y",
+			"name":            "app",
+			"none":            "",
+		})
+		assert.That(t, s.Has("empty")).False()
+		v, _ := s.Lookup("tags[1]")
+		assert.That(t, v.File).Equal(file)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		err := NewStorage().SetValues(url.Values{"a[": {"1"}}, 0)
+		assert.That(t, errors.Is(err, ErrInvalidPath)).True()
+
+		err = NewStorage().SetValues(url.Values{"a": {"1"}, "a.b": {"2"}}, 0)
+		assert.Error(t, err).Matches("property conflict at path a.b: existing value vs map")
+
+		err = NewStorage().SetValues(url.Values{"a": {"1", "2"}, "a.b": {"2"}}, 0)
+		assert.Error(t, err).Matches(`property conflict at path a.b: existing array vs map`)
+
+		// Nothing is stored if a name fails.
+		s := NewStorage()
+		assert.That(t, s.Set("b", "x", 0)).Nil()
+		err = s.SetValues(url.Values{"a": {"1"}, "z[": {"2"}}, 0)
+		assert.That(t, errors.Is(err, ErrInvalidPath)).True()
+		err = s.SetValues(url.Values{"a": {"1"}, "b": {"2"}, "b.c": {"3"}}, 0)
+		assert.Error(t, err).Matches("property conflict at path b.c")
+		assert.That(t, s.Data()).Equal(map[string]string{"b": "x"})
+		assertIndexed(t, s)
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		s := NewStorage()
+		for key, val := range map[string]any{
+			"servers[0].host": "a b",
+			"servers[0].port": 80,
+			"tags[0]":         "x",
+			"debug":           true,
+			"nil":             Nil,
+			"empty":           EmptyMap,
+		} {
+			assert.That(t, s.Set(key, val, 0)).Nil()
+		}
+		values := s.Values()
+		assert.That(t, values).Equal(url.Values{
+			"servers[0].host": {"a b"},
+			"servers[0].port": {"80"},
+			"tags[0]":         {"x"},
+			"debug":           {"true"},
+		})
+		query := values.Encode()
+		assert.That(t, query).Equal("debug=true&servers%5B0%5D.host=a+b&servers%5B0%5D.port=80&tags%5B0%5D=x")
+
+		parsed, err := url.ParseQuery(query)
+		assert.That(t, err).Nil()
+		r := NewStorage()
+		assert.That(t, r.SetValues(parsed, 0)).Nil()
+		assert.That(t, r.Data()).Equal(s.Data())
+	})
+}
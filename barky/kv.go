@@ -0,0 +1,268 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-spring/spring-base/util"
+)
+
+// KVPair is an entry of a KVSource.
+type KVPair struct {
+	Key   string // Slash-separated key, e.g. "/app/db/host".
+	Value string // Raw value of the key.
+}
+
+// KVSource is a hierarchical key/value store laid out like etcd, Consul
+// or Nacos, where keys are slash-separated paths such as "/app/db/host".
+// Keys are matched segment by segment, so the prefix "/app" covers
+// "/app/db/host" but not "/application".
+type KVSource interface {
+	// List returns the entries under prefix, including the entry stored
+	// at prefix itself, in unspecified order.
+	List(prefix string) ([]KVPair, error)
+
+	// Get returns the value stored at key, and whether it exists.
+	Get(key string) (string, bool, error)
+
+	// Watch returns a channel that receives a notification whenever the
+	// entries under prefix change. Notifications may be coalesced, so
+	// receivers should List again. The channel is closed when ctx is done.
+	Watch(ctx context.Context, prefix string) (<-chan struct{}, error)
+}
+
+// kvFlattener converts between slash-separated keys and Paths.
+var kvFlattener = &Flattener{
+	Separator:  "/",
+	IndexStyle: IndexSegment,
+}
+
+// SplitKVKey splits a slash-separated key such as "/app/servers/0/host"
+// into Paths. Leading and trailing slashes are ignored, and segments
+// consisting only of digits become array indices. The key "/" refers to
+// the root and yields an empty path.
+func SplitKVKey(key string) ([]Path, error) {
+	key = strings.Trim(key, "/")
+	if key == "" {
+		return []Path{}, nil
+	}
+	return kvFlattener.SplitPath(key)
+}
+
+// JoinKVKey joins Paths into a slash-separated key with a leading slash,
+// e.g. "/app/servers/0/host". It is the inverse of SplitKVKey.
+func JoinKVKey(path []Path) string {
+	return "/" + kvFlattener.JoinPath(path)
+}
+
+// LoadKV loads the entries under prefix from src into the Storage, as a
+// layer with the given name. Keys are made relative to prefix and turned
+// into flattened keys, so "/app/db/host" with the prefix "/app" becomes
+// "db.host"; dots within a segment thus nest further, as in
+// "/app/spring.application.name". Values are stored as strings. An
+// entry stored at prefix itself has no key below prefix and is rejected;
+// load its parent instead.
+func (l *Loader) LoadKV(name string, src KVSource, prefix string) error {
+	base, err := SplitKVKey(prefix)
+	if err != nil {
+		return util.FormatError(err, "load kv %s error", name)
+	}
+	pairs, err := src.List(prefix)
+	if err != nil {
+		return util.FormatError(err, "load kv %s error", name)
+	}
+
+	m := make(map[string]any, len(pairs))
+	for _, p := range pairs {
+		path, err := SplitKVKey(p.Key)
+		if err != nil {
+			return util.FormatError(err, "load kv %s error", name)
+		}
+		if slices.Equal(base, path) {
+			return util.FormatError(nil, "load kv %s error: key %s holds a value at the prefix itself", name, p.Key)
+		}
+		if !IsAncestor(base, path) {
+			return util.FormatError(nil, "load kv %s error: key %s is not below %s", name, p.Key, prefix)
+		}
+		m[JoinPath(path[len(base):])] = p.Value
+	}
+
+	l.conflicts = nil
+	idx, err := l.storage.TryAddFile(name)
+	if err != nil {
+		return util.FormatError(err, "load kv %s error", name)
+	}
+	l.layers = append(l.layers, Layer{
+		File:       name,
+		Index:      idx,
+		Precedence: len(l.layers),
+	})
+	if err = l.apply(m, idx); err != nil {
+		return util.FormatError(err, "load kv %s error", name)
+	}
+	if len(l.conflicts) > 0 {
+		return ConflictErrors(l.conflicts)
+	}
+	return nil
+}
+
+// DirSource is a KVSource backed by a local directory tree, with one
+// file per key: the key "/app/db/host" is stored in the file
+// "<Root>/app/db/host". It needs no network, which makes it a stand-in
+// for remote stores in tests and local development.
+type DirSource struct {
+	// Root is the directory that holds the keys.
+	Root string
+
+	// PollInterval is how often Watch checks for changes. Zero or less
+	// means DefaultPollInterval.
+	PollInterval time.Duration
+}
+
+// DefaultPollInterval is the poll interval of a DirSource that sets none.
+const DefaultPollInterval = time.Second
+
+// NewDirSource creates a DirSource rooted at dir that polls for changes
+// every DefaultPollInterval.
+func NewDirSource(dir string) *DirSource {
+	return &DirSource{Root: dir, PollInterval: DefaultPollInterval}
+}
+
+// file returns the file that stores key.
+func (d *DirSource) file(key string) (string, error) {
+	rel := strings.Trim(key, "/")
+	if rel == "" {
+		return d.Root, nil
+	}
+	if !filepath.IsLocal(rel) || strings.Contains(rel, "//") {
+		return "", util.FormatError(nil, "invalid kv key %q", key)
+	}
+	return filepath.Join(d.Root, filepath.FromSlash(rel)), nil
+}
+
+// List implements KVSource. Directories are traversed recursively and
+// every regular file becomes an entry.
+func (d *DirSource) List(prefix string) ([]KVPair, error) {
+	dir, err := d.file(prefix)
+	if err != nil {
+		return nil, err
+	}
+	var pairs []KVPair
+	err = filepath.WalkDir(dir, func(file string, e fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !e.Type().IsRegular() {
+			return nil
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(d.Root, file)
+		if err != nil {
+			return err
+		}
+		pairs = append(pairs, KVPair{
+			Key:   "/" + filepath.ToSlash(rel),
+			Value: string(data),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, util.FormatError(err, "list %s error", prefix)
+	}
+	return pairs, nil
+}
+
+// Get implements KVSource.
+func (d *DirSource) Get(key string) (string, bool, error) {
+	file, err := d.file(key)
+	if err != nil {
+		return "", false, err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		info, statErr := os.Stat(file)
+		if errors.Is(err, fs.ErrNotExist) || (statErr == nil && info.IsDir()) {
+			return "", false, nil
+		}
+		return "", false, util.FormatError(err, "get %s error", key)
+	}
+	return string(data), true, nil
+}
+
+// Watch implements KVSource by listing prefix every PollInterval and
+// comparing the result with the previous one.
+func (d *DirSource) Watch(ctx context.Context, prefix string) (<-chan struct{}, error) {
+	last, err := d.snapshot(prefix)
+	if err != nil {
+		return nil, err
+	}
+	interval := d.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ch := make(chan struct{}, 1)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			curr, err := d.snapshot(prefix)
+			if err != nil || maps.Equal(curr, last) {
+				continue
+			}
+			last = curr
+			select {
+			case ch <- struct{}{}:
+			default: // a notification is already pending
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// snapshot returns the entries under prefix as a map.
+func (d *DirSource) snapshot(prefix string) (map[string]string, error) {
+	pairs, err := d.List(prefix)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		m[p.Key] = p.Value
+	}
+	return m, nil
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spring/spring-base/testing/assert"
+)
+
+// sortedPairs sorts pairs by key for comparison.
+func sortedPairs(pairs []KVPair) []KVPair {
+	slices.SortFunc(pairs, func(a, b KVPair) int { return strings.Compare(a.Key, b.Key) })
+	return pairs
+}
+
+func TestKVKey(t *testing.T) {
+	path, err := SplitKVKey("/app/servers/0/host/")
+	assert.That(t, err).Nil()
+	assert.That(t, path).Equal([]Path{
+		{PathTypeKey, "app"}, {PathTypeKey, "servers"}, {PathTypeIndex, "0"}, {PathTypeKey, "host"},
+	})
+	assert.That(t, JoinKVKey(path)).Equal("/app/servers/0/host")
+	assert.That(t, JoinPath(path)).Equal("app.servers[0].host")
+
+	path, err = SplitKVKey("/")
+	assert.That(t, err).Nil()
+	assert.That(t, path).Equal([]Path{})
+	assert.That(t, JoinKVKey(path)).Equal("/")
+
+	_, err = SplitKVKey("/app//host")
+	assert.That(t, errors.Is(err, ErrInvalidPath)).True()
+}
+
+func TestDirSource(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app/name":           "demo",
+		"app/db/host":        "localhost",
+		"app/servers/0/port": "8080",
+		"application/name":   "other",
+	})
+	src := NewDirSource(dir)
+
+	t.Run("list", func(t *testing.T) {
+		pairs, err := src.List("/app")
+		assert.That(t, err).Nil()
+		assert.That(t, sortedPairs(pairs)).Equal([]KVPair{
+			{"/app/db/host", "localhost"},
+			{"/app/name", "demo"},
+			{"/app/servers/0/port", "8080"},
+		})
+
+		pairs, err = src.List("/app/name")
+		assert.That(t, err).Nil()
+		assert.That(t, pairs).Equal([]KVPair{{"/app/name", "demo"}})
+
+		pairs, err = src.List("/none")
+		assert.That(t, err).Nil()
+		assert.That(t, pairs).Nil()
+
+		_, err = src.List("/../etc")
+		assert.Error(t, err).Matches(`invalid kv key "/../etc"`)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		v, ok, err := src.Get("/app/db/host")
+		assert.That(t, err).Nil()
+		assert.That(t, ok).True()
+		assert.That(t, v).Equal("localhost")
+
+		_, ok, err = src.Get("/app/db")
+		assert.That(t, err).Nil()
+		assert.That(t, ok).False()
+
+		_, ok, err = src.Get("/app/none")
+		assert.That(t, err).Nil()
+		assert.That(t, ok).False()
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		src := NewDirSource(dir)
+		src.PollInterval = 5 * time.Millisecond
+		ctx, cancel := context.WithCancel(context.Background())
+		ch, err := src.Watch(ctx, "/app")
+		assert.That(t, err).Nil()
+
+		// Changes outside the prefix are ignored.
+		writeFiles(t, dir, map[string]string{"application/name": "changed"})
+		select {
+		case <-ch:
+			t.Fatal("unexpected notification")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		writeFiles(t, dir, map[string]string{"app/name": "changed"})
+		select {
+		case <-ch:
+		case <-time.After(5 * time.Second):
+			t.Fatal("no notification")
+		}
+
+		assert.That(t, os.Remove(filepath.Join(dir, "app/db/host"))).Nil()
+		select {
+		case <-ch:
+		case <-time.After(5 * time.Second):
+			t.Fatal("no notification")
+		}
+
+		cancel()
+		for range ch { // wait for the channel to be closed
+		}
+	})
+	t.Run("zero poll interval", func(t *testing.T) {
+		src := &DirSource{Root: dir}
+		ctx, cancel := context.WithCancel(context.Background())
+		ch, err := src.Watch(ctx, "/app")
+		assert.That(t, err).Nil()
+		cancel()
+		for range ch { // wait for the channel to be closed
+		}
+	})
+}
+
+func TestLoaderKV(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app/spring.application.name": "demo",
+		"app/db/host":                 "localhost",
+		"app/servers/0/port":          "8080",
+		"app/servers/1/port":          "8081",
+		"bad/a/0":                     "1",
+		"bad/a/b":                     "2",
+	})
+
+	s := NewStorage()
+	l := NewLoader(s)
+	err := l.LoadKV("kv:/app", NewDirSource(dir), "/app")
+	assert.That(t, err).Nil()
+	assert.That(t, s.Data()).Equal(map[string]string{
+		"spring.application.name": "demo",
+		"db.host":                 "localhost",
+		"servers[0].port":         "8080",
+		"servers[1].port":         "8081",
+	})
+	assert.That(t, l.Layers()).Equal([]Layer{
+		{File: "kv:/app", Index: 0, Precedence: 0},
+	})
+	v, _ := s.Lookup("db.host")
+	assert.That(t, s.fileName(v.File)).Equal("kv:/app")
+
+	err = NewLoader(NewStorage()).LoadKV("kv:/bad", NewDirSource(dir), "/bad")
+	assert.That(t, errors.Is(err, ErrConflict)).True()
+	assert.Error(t, err).Matches(`load kv kv:/bad error: .*property conflict at path a\[0\]: existing map`)
+
+	l = NewLoader(NewStorage())
+	l.CollectConflicts = true
+	err = l.LoadKV("kv:/bad", NewDirSource(dir), "/bad")
+	var conflicts ConflictErrors
+	assert.That(t, errors.As(err, &conflicts)).True()
+	assert.That(t, len(conflicts)).Equal(1)
+
+	err = NewLoader(NewStorage()).LoadKV("kv", NewDirSource(dir), "/app/db/host")
+	assert.Error(t, err).Matches(`load kv kv error: key /app/db/host holds a value at the prefix itself`)
+}
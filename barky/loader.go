@@ -0,0 +1,377 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-spring/spring-base/util"
+	"gopkg.in/yaml.v3"
+)
+
+// ImportKey is the key whose value lists further files to load, either
+// as a comma-separated string or as an array. Paths are relative to the
+// importing file, and a path prefixed with "optional:" may be missing.
+const ImportKey = "spring.config.import"
+
+// optionalPrefix marks an import that may be missing.
+const optionalPrefix = "optional:"
+
+// Decoder decodes the content of a file into one or more documents.
+// Formats that support multiple documents per file (such as YAML with
+// "---" separators) return them in order.
+type Decoder func(data []byte) ([]map[string]any, error)
+
+// DecodeJSON decodes one or more consecutive JSON objects.
+func DecodeJSON(data []byte) ([]map[string]any, error) {
+	var docs []map[string]any
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	for {
+		var m map[string]any
+		if err := d.Decode(&m); err != nil {
+			if errors.Is(err, io.EOF) {
+				return docs, nil
+			}
+			return nil, err
+		}
+		docs = append(docs, m)
+	}
+}
+
+// DecodeYAML decodes one or more YAML documents separated by "---".
+// Empty documents are skipped.
+func DecodeYAML(data []byte) ([]map[string]any, error) {
+	var docs []map[string]any
+	d := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var m map[string]any
+		if err := d.Decode(&m); err != nil {
+			if errors.Is(err, io.EOF) {
+				return docs, nil
+			}
+			return nil, err
+		}
+		if m != nil {
+			docs = append(docs, m)
+		}
+	}
+}
+
+// Layer records a file loaded by a Loader.
+type Layer struct {
+	File       string // Path of the file, as resolved by the Loader.
+	Index      int8   // Index of the file in the Storage.
+	Parent     string // File that imported this one, "" for top-level files.
+	Optional   bool   // Whether the file was imported as optional.
+	Missing    bool   // Whether the file was optional and not found.
+	Precedence int    // Order of application; higher values override lower.
+}
+
+// Loader loads files into a Storage. Files are decoded according to
+// their extension, flattened, and applied in order, so later files
+// override earlier ones. Files listed under ImportKey are loaded right
+// after the document that declares them, and thus override it.
+type Loader struct {
+	// KeepTypes preserves booleans and numbers, see Flattener.KeepTypes.
+	KeepTypes bool
+
+	// CollectConflicts makes Load keep going after a conflict, so that a
+	// single run reports every problem. Conflicting keys are skipped, see
+	// Storage.SetAll, and Load returns all conflicts as ConflictErrors
+	// once the files are loaded. Keys defined by more than one file are
+	// not conflicts; they are reported by Overrides.
+	CollectConflicts bool
+
+	// Registry, if set, is used to warn about deprecated keys, see
+	// Warnings.
+	Registry *Registry
+
+	storage   *Storage
+	decoders  map[string]Decoder
+	layers    []Layer
+	loading   []string         // files being loaded, for cycle detection
+	conflicts []*ConflictError // conflicts collected by the current Load
+	overrides []Override
+	warnings  []DeprecationWarning
+}
+
+// Override reports a value that a file replaced with its own. The later
+// file wins as usual; overrides are only recorded, e.g. to spot keys
+// that are set by mistake in several files.
+type Override struct {
+	Key             string // The overridden key.
+	File            string // The file whose value is kept.
+	Value           string // The value kept.
+	OverriddenFile  string // The file whose value was replaced.
+	OverriddenValue string // The value replaced.
+}
+
+// String returns a human-readable description of the override.
+func (o Override) String() string {
+	return fmt.Sprintf("key %s: %s (from %s) overrides %s (from %s)",
+		o.Key, o.Value, o.File, o.OverriddenValue, o.OverriddenFile)
+}
+
+// NewLoader creates a Loader that loads files into the given Storage.
+// Decoders for ".json", ".yaml" and ".yml" files are registered by
+// default.
+func NewLoader(s *Storage) *Loader {
+	return &Loader{
+		storage: s,
+		decoders: map[string]Decoder{
+			".json": DecodeJSON,
+			".yaml": DecodeYAML,
+			".yml":  DecodeYAML,
+		},
+	}
+}
+
+// RegisterDecoder registers a decoder for files with the given
+// extension (e.g. ".yaml"), replacing any previous one.
+func (l *Loader) RegisterDecoder(ext string, d Decoder) {
+	l.decoders[strings.ToLower(ext)] = d
+}
+
+// Layers returns the files loaded so far, in order of application.
+func (l *Loader) Layers() []Layer {
+	return slices.Clone(l.layers)
+}
+
+// Overrides returns the values replaced by a later file or import in
+// the files loaded so far, in order of loading.
+func (l *Loader) Overrides() []Override {
+	return slices.Clone(l.overrides)
+}
+
+// Warnings returns the deprecated keys found in the files loaded so far,
+// in order of loading: those deprecated by Registry, if set, and the
+// deprecated names of the Storage aliases (see Storage.AddAlias).
+func (l *Loader) Warnings() []DeprecationWarning {
+	return slices.Clone(l.warnings)
+}
+
+// Load loads a file, and recursively the files it imports, into the
+// Storage. It returns an error if a required file is missing, cannot
+// be decoded, conflicts with existing data, or imports itself.
+//
+// With CollectConflicts, conflicts do not stop loading; they are returned
+// together as ConflictErrors after the file and its imports are loaded.
+func (l *Loader) Load(file string) error {
+	l.conflicts = nil
+	if err := l.load(filepath.Clean(file), "", false); err != nil {
+		return err
+	}
+	if len(l.conflicts) > 0 {
+		return ConflictErrors(l.conflicts)
+	}
+	return nil
+}
+
+// load loads a single file imported by parent.
+func (l *Loader) load(file, parent string, optional bool) error {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return util.FormatError(err, "load file %s error", file)
+	}
+	if i := slices.Index(l.loading, abs); i >= 0 {
+		cycle := append(slices.Clone(l.loading[i:]), abs)
+		return util.FormatError(nil, "import cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if optional && errors.Is(err, os.ErrNotExist) {
+			l.layers = append(l.layers, Layer{
+				File:       file,
+				Index:      -1,
+				Parent:     parent,
+				Optional:   true,
+				Missing:    true,
+				Precedence: len(l.layers),
+			})
+			return nil
+		}
+		return util.FormatError(err, "load file %s error", file)
+	}
+
+	ext := strings.ToLower(filepath.Ext(file))
+	decode, ok := l.decoders[ext]
+	if !ok {
+		return util.FormatError(nil, "load file %s error: unsupported file type %q", file, ext)
+	}
+	docs, err := decode(data)
+	if err != nil {
+		return util.FormatError(err, "load file %s error", file)
+	}
+
+	idx, err := l.storage.TryAddFile(file)
+	if err != nil {
+		return util.FormatError(err, "load file %s error", file)
+	}
+	l.layers = append(l.layers, Layer{
+		File:       file,
+		Index:      idx,
+		Parent:     parent,
+		Optional:   optional,
+		Precedence: len(l.layers),
+	})
+
+	l.loading = append(l.loading, abs)
+	defer func() { l.loading = l.loading[:len(l.loading)-1] }()
+
+	f := NewFlattener()
+	f.KeepTypes = l.KeepTypes
+	dir := filepath.Dir(file)
+	for _, doc := range docs {
+		m := f.FlattenMap(doc)
+		imports, err := importsOf(m)
+		if err != nil {
+			return util.FormatError(err, "load file %s error", file)
+		}
+		for key := range m {
+			if key == ImportKey || strings.HasPrefix(key, ImportKey+"[") {
+				delete(m, key)
+			}
+		}
+		if err = l.apply(m, idx); err != nil {
+			return util.FormatError(err, "load file %s error", file)
+		}
+		for _, s := range imports {
+			opt := strings.HasPrefix(s, optionalPrefix)
+			s = strings.TrimPrefix(s, optionalPrefix)
+			if !filepath.IsAbs(s) {
+				s = filepath.Join(dir, s)
+			}
+			if err = l.load(s, file, opt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// apply stores a flattened document in the Storage, recording warnings
+// for deprecated keys and the values it overrides. With CollectConflicts,
+// conflicts are recorded instead of returned.
+func (l *Loader) apply(m map[string]any, idx int8) error {
+	if l.Registry != nil || len(l.storage.aliases) > 0 {
+		file := l.storage.fileName(idx)
+		for _, key := range util.OrderedMapKeys(m) {
+			w, ok := l.storage.aliasWarning(key, file)
+			if !ok && l.Registry != nil {
+				w, ok = l.Registry.deprecation(key, file)
+			}
+			if ok {
+				l.warnings = append(l.warnings, w)
+			}
+		}
+	}
+
+	// Values of other files that m may override.
+	previous := make(map[string]ValueInfo)
+	for key := range m {
+		if v, ok := leafValue(l.storage.lookup(key)); ok && v.File != idx {
+			previous[key] = v
+		}
+	}
+	err := l.set(m, idx)
+	for _, key := range util.OrderedMapKeys(previous) {
+		if v, ok := leafValue(l.storage.lookup(key)); ok && v.File == idx {
+			old := previous[key]
+			l.overrides = append(l.overrides, Override{
+				Key:             key,
+				File:            l.storage.fileName(idx),
+				Value:           v.Value,
+				OverriddenFile:  l.storage.fileName(old.File),
+				OverriddenValue: old.Value,
+			})
+		}
+	}
+	return err
+}
+
+// set stores a flattened document in the Storage.
+func (l *Loader) set(m map[string]any, idx int8) error {
+	if !l.CollectConflicts {
+		for _, key := range util.OrderedMapKeys(m) {
+			if err := l.storage.Set(key, m[key], idx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err := l.storage.SetAll(m, idx)
+	var conflicts ConflictErrors
+	if errors.As(err, &conflicts) {
+		l.conflicts = append(l.conflicts, conflicts...)
+		return nil
+	}
+	return err
+}
+
+// importsOf extracts the import list of a flattened document.
+func importsOf(m map[string]any) ([]string, error) {
+	if v, ok := m[ImportKey]; ok {
+		if _, ok = v.(Marker); ok { // nil or empty list
+			return nil, nil
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, util.FormatError(nil, "invalid value for key %s", ImportKey)
+		}
+		var imports []string
+		for str := range strings.SplitSeq(s, ",") {
+			if str = strings.TrimSpace(str); str != "" {
+				imports = append(imports, str)
+			}
+		}
+		return imports, nil
+	}
+
+	type entry struct {
+		index int
+		file  string
+	}
+	var entries []entry
+	for key, v := range m {
+		if !strings.HasPrefix(key, ImportKey+"[") {
+			continue
+		}
+		index, err := strconv.Atoi(strings.TrimSuffix(key[len(ImportKey)+1:], "]"))
+		s, ok := v.(string)
+		if err != nil || !ok {
+			return nil, util.FormatError(nil, "invalid value for key %s", key)
+		}
+		entries = append(entries, entry{index, strings.TrimSpace(s)})
+	}
+	slices.SortFunc(entries, func(a, b entry) int { return a.index - b.index })
+	imports := make([]string, 0, len(entries))
+	for _, e := range entries {
+		imports = append(imports, e.file)
+	}
+	return imports, nil
+}
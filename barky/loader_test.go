@@ -0,0 +1,245 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/spring-base/testing/assert"
+)
+
+// writeFiles writes the given files into dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		err := os.MkdirAll(filepath.Dir(file), os.ModePerm)
+		assert.That(t, err).Nil()
+		err = os.WriteFile(file, []byte(content), 0644)
+		assert.That(t, err).Nil()
+	}
+}
+
+func TestLoader(t *testing.T) {
+
+	t.Run("imports", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"app.json": `{
+				"name": "app", "port": 8080,
+				"spring": {"config": {"import": ["conf/common.json", "optional:local.json", "optional:none.json"]}}
+			}`,
+			"conf/common.json": `{"port": 9090, "db": {"host": "db"}, "spring.config.import": "more.json"}`,
+			"conf/more.json":   `{"db": {"user": "root"}}`,
+			"local.json":       `{"name": "local"}`,
+		})
+
+		s := NewStorage()
+		l := NewLoader(s)
+		err := l.Load(filepath.Join(dir, "app.json"))
+		assert.That(t, err).Nil()
+		assert.That(t, s.Data()).Equal(map[string]string{
+			"name":    "local",
+			"port":    "9090",
+			"db.host": "db",
+			"db.user": "root",
+		})
+		assert.That(t, s.Has("spring")).False()
+
+		app := filepath.Join(dir, "app.json")
+		common := filepath.Join(dir, "conf/common.json")
+		more := filepath.Join(dir, "conf/more.json")
+		local := filepath.Join(dir, "local.json")
+		assert.That(t, l.Layers()).Equal([]Layer{
+			{File: app, Index: 0, Precedence: 0},
+			{File: common, Index: 1, Parent: app, Precedence: 1},
+			{File: more, Index: 2, Parent: common, Precedence: 2},
+			{File: local, Index: 3, Parent: app, Optional: true, Precedence: 3},
+			{File: filepath.Join(dir, "none.json"), Index: -1, Parent: app, Optional: true, Missing: true, Precedence: 4},
+		})
+
+		v, _ := s.Lookup("port")
+		assert.That(t, v.File).Equal(s.RawFile()[common])
+
+		overrides := l.Overrides()
+		assert.That(t, overrides).Equal([]Override{
+			{Key: "port", File: common, Value: "9090", OverriddenFile: app, OverriddenValue: "8080"},
+			{Key: "name", File: local, Value: "local", OverriddenFile: app, OverriddenValue: "app"},
+		})
+		assert.That(t, overrides[1].String()).Equal("key name: local (from " + local + ") overrides app (from " + app + ")")
+	})
+
+	t.Run("keep types", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{"app.json": `{"port": 8080, "debug": true}`})
+
+		s := NewStorage()
+		l := NewLoader(s)
+		l.KeepTypes = true
+		err := l.Load(filepath.Join(dir, "app.json"))
+		assert.That(t, err).Nil()
+		v, _ := s.Lookup("port")
+		assert.That(t, v.Kind).Equal(KindInt)
+		v, _ = s.Lookup("debug")
+		assert.That(t, v.Kind).Equal(KindBool)
+	})
+
+	t.Run("multiple documents", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"app.json": `{"a": "1", "spring.config.import": "b.json"} {"a": "3", "c": "3"}`,
+			"b.json":   `{"a": "2", "b": "2"}`,
+		})
+		s := NewStorage()
+		err := NewLoader(s).Load(filepath.Join(dir, "app.json"))
+		assert.That(t, err).Nil()
+		assert.That(t, s.Data()).Equal(map[string]string{"a": "3", "b": "2", "c": "3"})
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"app.yaml": `
+name: app
+spring.config.import: ['common.yml', 'optional:local.yaml']
+---
+---
+port: 8080
+`,
+			"common.yml": "db:\n  host: db\n  port: 5432\n",
+		})
+
+		s := NewStorage()
+		l := NewLoader(s)
+		l.KeepTypes = true
+		err := l.Load(filepath.Join(dir, "app.yaml"))
+		assert.That(t, err).Nil()
+		assert.That(t, s.Data()).Equal(map[string]string{
+			"name":    "app",
+			"port":    "8080",
+			"db.host": "db",
+			"db.port": "5432",
+		})
+		v, _ := s.Lookup("db.port")
+		assert.That(t, v.Kind).Equal(KindInt)
+		assert.That(t, l.Layers()[2].Missing).True()
+
+		_, err = DecodeYAML([]byte("a: [1"))
+		assert.That(t, err).NotNil()
+	})
+
+	t.Run("custom decoder", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{"app.txt": "a=1\nb=2"})
+
+		s := NewStorage()
+		l := NewLoader(s)
+		l.RegisterDecoder(".TXT", func(data []byte) ([]map[string]any, error) {
+			m := make(map[string]any)
+			for line := range strings.Lines(string(data)) {
+				k, v, _ := strings.Cut(strings.TrimSpace(line), "=")
+				m[k] = v
+			}
+			return []map[string]any{m}, nil
+		})
+		err := l.Load(filepath.Join(dir, "app.txt"))
+		assert.That(t, err).Nil()
+		assert.That(t, s.Data()).Equal(map[string]string{"a": "1", "b": "2"})
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"a.json": `{"spring.config.import": "b.json"}`,
+			"b.json": `{"spring.config.import": ["optional:a.json"]}`,
+		})
+		err := NewLoader(NewStorage()).Load(filepath.Join(dir, "a.json"))
+		assert.Error(t, err).Matches(`import cycle: .*a.json -> .*b.json -> .*a.json`)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"missing.json":  `{"spring.config.import": "none.json"}`,
+			"bad.json":      `{"a": `,
+			"app.ini":       `a=1`,
+			"conflict.json": `{"a": "1", "spring.config.import": "other.json"}`,
+			"other.json":    `{"a": {"b": "2"}}`,
+			"import.json":   `{"spring.config.import": 1}`,
+		})
+
+		err := NewLoader(NewStorage()).Load(filepath.Join(dir, "missing.json"))
+		assert.That(t, errors.Is(err, os.ErrNotExist)).True()
+
+		err = NewLoader(NewStorage()).Load(filepath.Join(dir, "bad.json"))
+		assert.Error(t, err).Matches("load file .*bad.json error: unexpected EOF")
+
+		err = NewLoader(NewStorage()).Load(filepath.Join(dir, "app.ini"))
+		assert.Error(t, err).Matches(`unsupported file type ".ini"`)
+
+		err = NewLoader(NewStorage()).Load(filepath.Join(dir, "conflict.json"))
+		assert.That(t, errors.Is(err, ErrConflict)).True()
+		assert.Error(t, err).Matches(`load file .*other.json error: property conflict at path a.b: existing value \(from .*conflict.json\) vs map`)
+
+		l := NewLoader(NewStorage())
+		l.KeepTypes = true
+		err = l.Load(filepath.Join(dir, "import.json"))
+		assert.Error(t, err).Matches("invalid value for key spring.config.import")
+	})
+
+	t.Run("collect conflicts", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"app.json":   `{"a": "1", "b": [1], "c": "x", "spring.config.import": "other.json"}`,
+			"other.json": `{"a": {"x": "2"}, "b": {"y": "3"}, "c": "y", "d": "4"}`,
+			"ok.json":    `{"e": "5"}`,
+		})
+
+		s := NewStorage()
+		l := NewLoader(s)
+		l.CollectConflicts = true
+		err := l.Load(filepath.Join(dir, "app.json"))
+
+		var conflicts ConflictErrors
+		assert.That(t, errors.As(err, &conflicts)).True()
+		assert.That(t, len(conflicts)).Equal(2)
+		assert.Error(t, err).Matches(`^2 property conflicts:
+	property conflict at path a.x: existing value \(from .*app.json\) vs map \(from .*other.json\)
+	property conflict at path b.y: existing array \(from .*app.json\) vs map \(from .*other.json\)$`)
+		assert.That(t, s.Data()).Equal(map[string]string{
+			"a":    "1",
+			"b[0]": "1",
+			"c":    "y",
+			"d":    "4",
+		})
+
+		// Overrides are not conflicts.
+		app, other := filepath.Join(dir, "app.json"), filepath.Join(dir, "other.json")
+		assert.That(t, l.Overrides()).Equal([]Override{
+			{Key: "c", File: other, Value: "y", OverriddenFile: app, OverriddenValue: "x"},
+		})
+
+		// Conflicts are reset on every Load.
+		err = l.Load(filepath.Join(dir, "ok.json"))
+		assert.That(t, err).Nil()
+		assert.That(t, s.Get("e")).Equal("5")
+	})
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"fmt"
+	"iter"
+)
+
+// Meta documents a configuration key.
+type Meta struct {
+	Description     string // Human-readable description.
+	Type            string // Expected type, e.g. "string" or "duration".
+	DeprecatedSince string // Version that deprecated the key, "" if not deprecated.
+	Replacement     string // Key to use instead; a key with a replacement is deprecated.
+	Sensitive       bool   // Whether the value is a secret that must not be shown.
+}
+
+// Deprecated reports whether the key is deprecated, i.e. whether it has
+// a DeprecatedSince version or a Replacement.
+func (m Meta) Deprecated() bool {
+	return m.DeprecatedSince != "" || m.Replacement != ""
+}
+
+// registryEntry is a pattern registered in a Registry.
+type registryEntry struct {
+	pattern *Pattern
+	meta    Meta
+}
+
+// Registry holds the metadata of configuration keys, registered by
+// key pattern (see CompilePattern), so that one entry can document
+// e.g. every "servers[*].port".
+type Registry struct {
+	entries []registryEntry
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register attaches metadata to the keys matching pattern. Registering
+// the same pattern again replaces its metadata.
+func (r *Registry) Register(pattern string, meta Meta) error {
+	p, err := CompilePattern(pattern)
+	if err != nil {
+		return err
+	}
+	for i, e := range r.entries {
+		if e.pattern.String() == pattern {
+			r.entries[i].meta = meta
+			return nil
+		}
+	}
+	r.entries = append(r.entries, registryEntry{pattern: p, meta: meta})
+	return nil
+}
+
+// All returns an iterator over the registered patterns and their
+// metadata, in registration order.
+func (r *Registry) All() iter.Seq2[string, Meta] {
+	return func(yield func(string, Meta) bool) {
+		for _, e := range r.entries {
+			if !yield(e.pattern.String(), e.meta) {
+				return
+			}
+		}
+	}
+}
+
+// Lookup returns the metadata of a flattened key, and whether any
+// pattern matches it. If several patterns match, the most specific one
+// wins: the one with the most literal segments, and "*" or "[*]" over
+// "**". Ties go to the pattern registered first.
+func (r *Registry) Lookup(key string) (Meta, bool) {
+	path, err := SplitPath(key)
+	if err != nil {
+		return Meta{}, false
+	}
+	return r.lookup(path)
+}
+
+// lookup returns the metadata of a parsed key.
+func (r *Registry) lookup(path []Path) (Meta, bool) {
+	var (
+		best  *registryEntry
+		score int
+	)
+	for i := range r.entries {
+		e := &r.entries[i]
+		if !e.pattern.MatchPath(path) {
+			continue
+		}
+		if n := e.pattern.specificity(); best == nil || n > score {
+			best, score = e, n
+		}
+	}
+	if best == nil {
+		return Meta{}, false
+	}
+	return best.meta, true
+}
+
+// DeprecationWarning reports a deprecated key found in a file.
+type DeprecationWarning struct {
+	Key         string // The deprecated key.
+	File        string // The file that set the key, if known.
+	Since       string // Version that deprecated the key.
+	Replacement string // Key to use instead, if any.
+}
+
+// String returns a human-readable warning.
+func (w DeprecationWarning) String() string {
+	msg := "key " + w.Key
+	if w.File != "" {
+		msg += fmt.Sprintf(" (from %s)", w.File)
+	}
+	msg += " is deprecated"
+	if w.Since != "" {
+		msg += " since " + w.Since
+	}
+	if w.Replacement != "" {
+		msg += ", use " + w.Replacement + " instead"
+	}
+	return msg
+}
+
+// deprecation returns the warning for key set by file, if the key is
+// deprecated.
+func (r *Registry) deprecation(key, file string) (DeprecationWarning, bool) {
+	m, ok := r.Lookup(key)
+	if !ok || !m.Deprecated() {
+		return DeprecationWarning{}, false
+	}
+	return DeprecationWarning{
+		Key:         key,
+		File:        file,
+		Since:       m.DeprecatedSince,
+		Replacement: m.Replacement,
+	}, true
+}
+
+// Deprecations returns a warning for every deprecated key stored in s,
+// in tree order (see Walk).
+func (r *Registry) Deprecations(s *Storage) []DeprecationWarning {
+	var warnings []DeprecationWarning
+	for key, v := range s.Walk("") {
+		if w, ok := r.deprecation(key, s.fileName(v.File)); ok {
+			warnings = append(warnings, w)
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/go-spring/spring-base/testing/assert"
+)
+
+// newTestRegistry creates a Registry used by the tests.
+func newTestRegistry(t *testing.T) *Registry {
+	r := NewRegistry()
+	for pattern, meta := range map[string]Meta{
+		"**.password":     {Description: "A password.", Sensitive: true},
+		"db.password":     {Description: "Database password.", Type: "string", Sensitive: true},
+		"db.**":           {Description: "Database settings."},
+		"db.*":            {Description: "A database setting."},
+		"servers[*].port": {Description: "Server port.", Type: "int"},
+		"db.url":          {DeprecatedSince: "v2.0", Replacement: "db.host"},
+		"legacy.**":       {DeprecatedSince: "v1.5"},
+		"old.name":        {Replacement: "new.name"},
+	} {
+		assert.That(t, r.Register(pattern, meta)).Nil()
+	}
+	return r
+}
+
+func TestRegistry(t *testing.T) {
+
+	t.Run("lookup", func(t *testing.T) {
+		r := newTestRegistry(t)
+		for key, desc := range map[string]string{
+			"db.password":     "Database password.",
+			"cache.password":  "A password.",
+			"db.host":         "A database setting.",
+			"db.pool.size":    "Database settings.",
+			"servers[3].port": "Server port.",
+		} {
+			m, ok := r.Lookup(key)
+			assert.That(t, ok).True()
+			assert.That(t, m.Description).Equal(desc, key)
+		}
+
+		m, _ := r.Lookup("db.password")
+		assert.That(t, m).Equal(Meta{Description: "Database password.", Type: "string", Sensitive: true})
+
+		_, ok := r.Lookup("servers.port")
+		assert.That(t, ok).False()
+		_, ok = r.Lookup("a[")
+		assert.That(t, ok).False()
+	})
+
+	t.Run("register", func(t *testing.T) {
+		r := NewRegistry()
+		assert.That(t, r.Register("a.*", Meta{Description: "x"})).Nil()
+		assert.That(t, r.Register("b", Meta{Description: "y"})).Nil()
+		assert.That(t, r.Register("a.*", Meta{Description: "z"})).Nil()
+		assert.Error(t, r.Register("a[", Meta{})).Matches("unclosed")
+
+		var patterns []string
+		for pattern, m := range r.All() {
+			patterns = append(patterns, pattern+"="+m.Description)
+		}
+		assert.That(t, patterns).Equal([]string{"a.*=z", "b=y"})
+
+		for range r.All() {
+			break
+		}
+	})
+
+	t.Run("deprecations", func(t *testing.T) {
+		r := newTestRegistry(t)
+		s := NewStorage()
+		file := s.AddFile("app.json")
+		assert.That(t, s.Set("db.url", "x", file)).Nil()
+		assert.That(t, s.Set("db.host", "x", file)).Nil()
+		assert.That(t, s.Set("legacy.mode", "on", 5)).Nil()
+		assert.That(t, s.Set("old.name", "x", file)).Nil()
+
+		warnings := r.Deprecations(s)
+		assert.That(t, warnings).Equal([]DeprecationWarning{
+			{Key: "db.url", File: "app.json", Since: "v2.0", Replacement: "db.host"},
+			{Key: "legacy.mode", Since: "v1.5"},
+			{Key: "old.name", File: "app.json", Replacement: "new.name"},
+		})
+		assert.That(t, warnings[0].String()).Equal("key db.url (from app.json) is deprecated since v2.0, use db.host instead")
+		assert.That(t, warnings[1].String()).Equal("key legacy.mode is deprecated since v1.5")
+		assert.That(t, warnings[2].String()).Equal("key old.name (from app.json) is deprecated, use new.name instead")
+	})
+
+	t.Run("loader", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"app.json":   `{"db": {"url": "x"}, "spring.config.import": "other.json"}`,
+			"other.json": `{"db": {"host": "h"}, "legacy": {"a": 1, "b": 2}}`,
+		})
+
+		l := NewLoader(NewStorage())
+		assert.That(t, l.Load(filepath.Join(dir, "app.json"))).Nil()
+		assert.That(t, l.Warnings()).Nil()
+
+		l = NewLoader(NewStorage())
+		l.Registry = newTestRegistry(t)
+		assert.That(t, l.Load(filepath.Join(dir, "app.json"))).Nil()
+		app := filepath.Join(dir, "app.json")
+		other := filepath.Join(dir, "other.json")
+		assert.That(t, l.Warnings()).Equal([]DeprecationWarning{
+			{Key: "db.url", File: app, Since: "v2.0", Replacement: "db.host"},
+			{Key: "legacy.a", File: other, Since: "v1.5"},
+			{Key: "legacy.b", File: other, Since: "v1.5"},
+		})
+	})
+}
@@ -0,0 +1,706 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-spring/spring-base/util"
+)
+
+// patchFlattener flattens patch values, keeping their types.
+var patchFlattener = func() *Flattener {
+	f := NewFlattener()
+	f.KeepTypes = true
+	return f
+}()
+
+// patchOp is an operation of an RFC 6902 JSON Patch.
+type patchOp struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ApplyPatch applies an RFC 6902 JSON Patch document, i.e. a JSON array
+// of "add", "remove", "replace", "move", "copy" and "test" operations
+// whose paths are JSON Pointers (see SplitPointer). Pointer segments
+// made of digits name map keys when they refer into a map, and "-"
+// refers to the end of an array. Adding or removing array elements
+// shifts the following ones, and removing the last child of a
+// container leaves an empty map or slice behind. Moved and copied
+// arrays keep their indices, even sparse ones.
+//
+// The patch is applied atomically: either all operations succeed, or
+// the Storage is left unchanged and a *PatchError describes the
+// failed operation. Structural conflicts are reported like by Set.
+// Every value written by the patch, including moved and copied ones,
+// is attributed to the file registered under name. Values stored as
+// strings, e.g. by a Loader without KeepTypes, match "test" values by
+// their text.
+func (s *Storage) ApplyPatch(name string, patch []byte) error {
+	if s.frozen != nil {
+		return errFrozen("apply patch " + name)
+	}
+	var ops []patchOp
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return util.FormatError(err, "invalid JSON patch %s", name)
+	}
+	w := s.workingCopy()
+	file, err := w.TryAddFile(name)
+	if err != nil {
+		return util.FormatError(err, "apply patch %s", name)
+	}
+	for i, op := range ops {
+		if err := w.applyOp(op, file); err != nil {
+			var e *ConflictError
+			if errors.As(err, &e) {
+				e.AttemptedFile = name
+			}
+			pe := &PatchError{Patch: name, Index: i, Op: op.Op, Err: err}
+			if op.Path != nil {
+				pe.Path = *op.Path
+			}
+			return pe
+		}
+	}
+	s.commit(w)
+	return nil
+}
+
+// applyOp applies a single JSON Patch operation.
+func (s *Storage) applyOp(op patchOp, file int8) error {
+	if op.Path == nil {
+		return util.FormatError(nil, "missing path")
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return util.FormatError(nil, "missing value")
+		}
+		val, err := decodePatchValue(op.Value)
+		if err != nil {
+			return err
+		}
+		path, err := s.resolvePointer(*op.Path)
+		if err != nil {
+			return err
+		}
+		switch op.Op {
+		case "add":
+			return s.patchAdd(path, val, file, true)
+		case "replace":
+			if _, ok := s.nodeAt(path); !ok {
+				return errNotExist(path)
+			}
+			return s.patchAdd(path, val, file, false)
+		default:
+			return s.patchTest(path, val)
+		}
+	case "remove":
+		path, err := s.resolvePointer(*op.Path)
+		if err != nil {
+			return err
+		}
+		return s.patchRemove(path, file)
+	case "move", "copy":
+		if op.From == nil {
+			return util.FormatError(nil, "missing from")
+		}
+		from, err := s.resolvePointer(*op.From)
+		if err != nil {
+			return err
+		}
+		n, ok := s.nodeAt(from)
+		if !ok {
+			return errNotExist(from)
+		}
+		val := s.subtree(n, JoinPath(from))
+		if op.Op == "move" {
+			if *op.From == *op.Path {
+				return nil
+			}
+			if strings.HasPrefix(*op.Path, *op.From+"/") {
+				return util.FormatError(nil, "cannot move %s into itself", *op.From)
+			}
+			if err := s.patchRemove(from, file); err != nil {
+				return err
+			}
+		}
+		// The target is resolved after the removal, which may have
+		// shifted array elements.
+		path, err := s.resolvePointer(*op.Path)
+		if err != nil {
+			return err
+		}
+		return s.patchAdd(path, val, file, true)
+	default:
+		return util.FormatError(nil, "unknown operation %q", op.Op)
+	}
+}
+
+// ApplyMergePatch applies an RFC 7396 JSON merge patch: the members of
+// a JSON object are merged into the map at the same place recursively,
+// null members remove keys, and any other value, including an array,
+// replaces the target. Like ApplyPatch, the merge is atomic, keeps the
+// structural conflict checks and attributes the values it writes to the
+// file registered under name.
+func (s *Storage) ApplyMergePatch(name string, patch []byte) error {
+	if s.frozen != nil {
+		return errFrozen("apply merge patch " + name)
+	}
+	val, err := decodePatchValue(patch)
+	if err != nil {
+		return util.FormatError(err, "invalid merge patch %s", name)
+	}
+	w := s.workingCopy()
+	file, err := w.TryAddFile(name)
+	if err != nil {
+		return util.FormatError(err, "apply merge patch %s", name)
+	}
+	if err = w.mergePatch(nil, val, file); err != nil {
+		var e *ConflictError
+		if errors.As(err, &e) {
+			e.AttemptedFile = name
+		}
+		return util.FormatError(err, "apply merge patch %s", name)
+	}
+	s.commit(w)
+	return nil
+}
+
+// mergePatch merges a merge patch value into the node at path.
+func (s *Storage) mergePatch(path []Path, patch any, file int8) error {
+	m, ok := patch.(map[string]any)
+	if !ok {
+		return s.patchAdd(path, patch, file, false)
+	}
+	if n, ok := s.nodeAt(path); !ok || !isMap(n) {
+		if len(path) == 0 {
+			s.dropRoot()
+			s.transformed = nil
+		} else if err := s.patchAdd(path, map[string]any{}, file, false); err != nil {
+			return err
+		}
+	}
+	for _, k := range util.OrderedMapKeys(m) {
+		child, err := appendForeignKey(slices.Clone(path), k)
+		if err != nil {
+			return err
+		}
+		if m[k] == nil {
+			if _, ok := s.nodeAt(child); ok {
+				if err = s.patchRemove(child, file); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err = s.mergePatch(child, m[k], file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isMap reports whether n is a map, possibly an empty one.
+func isMap(n *treeNode) bool {
+	if n.isLeaf() {
+		return n.Value.Kind == KindEmptyMap
+	}
+	return n.Type == PathTypeKey
+}
+
+// workState tracks the changes of a working copy, see workingCopy.
+type workState struct {
+	intern  map[string]string  // interned segments of the original Storage
+	owned   map[*treeNode]bool // nodes copied or created by the working copy
+	removed []removedNode      // subtrees detached from the tree
+	moved   []string           // keys that shiftArray moved subtrees to
+}
+
+// removedNode is a subtree detached from the tree of a working copy,
+// with the flattened key it had.
+type removedNode struct {
+	key string
+	n   *treeNode
+}
+
+// workingCopy returns a copy of the Storage that a patch can modify, and
+// that replaces the Storage once the patch succeeded, see commit. The
+// copy shares the tree of s: the nodes a patch modifies are copied
+// first, along with their ancestors (see mutableAt), and new segments
+// are interned into a map of its own, so that a failed patch leaves s
+// untouched. The copy has no key index, since patches also remove nodes.
+func (s *Storage) workingCopy() *Storage {
+	w := *s
+	w.file = maps.Clone(s.file)
+	w.transformed = maps.Clone(s.transformed)
+	w.intern = make(map[string]string)
+	w.index = nil
+	w.work = &workState{intern: s.intern, owned: make(map[*treeNode]bool)}
+	return &w
+}
+
+// commit replaces s with its working copy w. It merges the segments
+// interned by w and, unless s is compact, updates the index for the
+// nodes that w removed, moved, copied or created.
+func (s *Storage) commit(w *Storage) {
+	maps.Copy(s.intern, w.intern)
+	if s.index != nil {
+		for _, r := range w.work.removed {
+			s.unindexNode(r.n, r.key)
+		}
+		w.index = s.index
+		for _, key := range w.work.moved {
+			if n, ok := w.scan(key); ok && !n.isLeaf() {
+				w.indexNode(n, key)
+			}
+		}
+		if w.root != nil {
+			w.indexOwned(w.root, "")
+		}
+	}
+	w.intern, w.work = s.intern, nil
+	*s = *w
+}
+
+// unindexNode removes key and the keys of the containers below the
+// node n, which was stored under key, from the index.
+func (s *Storage) unindexNode(n *treeNode, key string) {
+	delete(s.index, key)
+	for elem, c := range n.Data {
+		if !c.isLeaf() {
+			s.unindexNode(c, childKey(key, n.Type, elem))
+		}
+	}
+}
+
+// indexOwned adds the containers that a working copy copied or created
+// to the index, starting from n whose flattened key is key.
+func (s *Storage) indexOwned(n *treeNode, key string) {
+	if n.isLeaf() || !s.work.owned[n] {
+		return
+	}
+	s.index[key] = n
+	for elem, c := range n.Data {
+		s.indexOwned(c, childKey(key, n.Type, elem))
+	}
+}
+
+// own returns a node of a working copy that can be modified in place:
+// n itself if the working copy owns it, or else a copy of it that
+// shares its children.
+func (s *Storage) own(n *treeNode) *treeNode {
+	if s.work.owned[n] {
+		return n
+	}
+	c := &treeNode{Type: n.Type, Data: maps.Clone(n.Data), Value: n.Value}
+	s.work.owned[c] = true
+	return c
+}
+
+// mutableAt returns the node at path like nodeAt, for modifying it. In a
+// working copy, the node and its ancestors are copied first if needed.
+func (s *Storage) mutableAt(path []Path) (*treeNode, bool) {
+	if s.work == nil {
+		return s.nodeAt(path)
+	}
+	if s.root == nil {
+		return nil, false
+	}
+	s.root = s.own(s.root)
+	n := s.root
+	for _, p := range path {
+		c, ok := n.Data[p.Elem]
+		if !ok || n.Type != p.Type {
+			return nil, false
+		}
+		if o := s.own(c); o != c {
+			n.Data[s.internString(p.Elem)] = o
+			c = o
+		}
+		n = c
+	}
+	return n, true
+}
+
+// detach removes the child elem of the node n. The flattened key of the
+// child is key, which a working copy records to update the index.
+func (s *Storage) detach(n *treeNode, elem, key string) {
+	if c, ok := n.Data[elem]; ok && s.work != nil {
+		s.work.removed = append(s.work.removed, removedNode{key, c})
+	}
+	delete(n.Data, elem)
+}
+
+// dropRoot removes the whole tree.
+func (s *Storage) dropRoot() {
+	if s.root != nil && s.work != nil {
+		s.work.removed = append(s.work.removed, removedNode{"", s.root})
+	}
+	s.root = nil
+}
+
+// decodePatchValue decodes a JSON value, keeping numbers as json.Number.
+func decodePatchValue(data []byte) (any, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, util.FormatError(err, "invalid value")
+	}
+	return v, nil
+}
+
+// resolvePointer parses a JSON Pointer against the current tree: digit
+// segments that refer into a map are map keys, and "-" in an array is
+// the index after its last element.
+func (s *Storage) resolvePointer(ptr string) ([]Path, error) {
+	path, err := SplitPointer(ptr)
+	if err != nil {
+		return nil, err
+	}
+	n := s.root
+	for i, p := range path {
+		if n == nil {
+			break
+		}
+		typ, size := n.Type, 0
+		if n.isLeaf() {
+			switch n.Value.Kind {
+			case KindEmptyMap:
+				typ = PathTypeKey
+			case KindEmptySlice:
+				typ = PathTypeIndex
+			default:
+				return path, nil
+			}
+		} else if typ == PathTypeIndex {
+			if size, err = arrayLen(n); err != nil {
+				return nil, err
+			}
+		}
+		switch {
+		case typ == PathTypeKey && p.Type == PathTypeIndex:
+			path[i].Type = PathTypeKey
+		case typ == PathTypeIndex && p.Type == PathTypeKey && p.Elem == "-":
+			path[i] = Path{Type: PathTypeIndex, Elem: strconv.Itoa(size)}
+		}
+		n = n.Data[path[i].Elem]
+	}
+	return path, nil
+}
+
+// nodeAt returns the node at path; the empty path refers to the root.
+func (s *Storage) nodeAt(path []Path) (*treeNode, bool) {
+	return s.lookup(JoinPath(path))
+}
+
+// arrayLen returns the length of the array node n, i.e. its highest
+// index plus one.
+func arrayLen(n *treeNode) (int, error) {
+	size := 0
+	for elem := range n.Data {
+		i, err := parseIndex(elem)
+		if err != nil {
+			return 0, err
+		}
+		size = max(size, i+1)
+	}
+	return size, nil
+}
+
+// parseIndex parses an array index stored in the tree. Indices are
+// unsigned integers, but may not fit in an int; math.MaxInt is rejected
+// as well so that the index of the next element doesn't overflow.
+func parseIndex(elem string) (int, error) {
+	i, err := strconv.Atoi(elem)
+	if err != nil || i == math.MaxInt {
+		return 0, util.FormatError(nil, "index %s is out of range", elem)
+	}
+	return i, nil
+}
+
+// errNotExist returns the error for a path that doesn't exist.
+func errNotExist(path []Path) error {
+	return util.FormatError(nil, "path %q does not exist", JoinPointer(path))
+}
+
+// patchAdd stores val at path, replacing any existing value there. With
+// insert, an array element is inserted, shifting the following ones.
+func (s *Storage) patchAdd(path []Path, val any, file int8, insert bool) error {
+	if len(path) == 0 {
+		switch v := val.(type) {
+		case map[string]any, []any:
+		case patchTree:
+			if leaf, ok := v[""]; ok && leaf.Kind != KindEmptyMap && leaf.Kind != KindEmptySlice {
+				return util.FormatError(nil, "the document must be a map or an array")
+			}
+		default:
+			return util.FormatError(nil, "the document must be a map or an array")
+		}
+		s.dropRoot()
+		s.transformed = nil
+		return s.setValue("", val, file)
+	}
+
+	parentPath, last := path[:len(path)-1], path[len(path)-1]
+	parent, ok := s.mutableAt(parentPath)
+	if !ok {
+		if len(parentPath) > 0 {
+			return errNotExist(parentPath)
+		}
+		return s.setValue(JoinPath(path), val, file)
+	}
+
+	// An empty container becomes a real one to receive the value.
+	if parent.isLeaf() {
+		if k := parent.Value.Kind; k == KindEmptyMap && last.Type == PathTypeKey ||
+			k == KindEmptySlice && last.Type == PathTypeIndex {
+			parent.Type = last.Type
+			parent.Data = make(map[string]*treeNode)
+			parent.Value = ValueInfo{}
+		}
+	}
+
+	if !parent.isLeaf() && parent.Type == last.Type {
+		if last.Type == PathTypeIndex && insert {
+			size, err := arrayLen(parent)
+			if err != nil {
+				return err
+			}
+			i, err := strconv.Atoi(last.Elem)
+			if err != nil || i > size {
+				return util.FormatError(nil, "index %s out of range [0, %d]", last.Elem, size)
+			}
+			if err = s.shiftArray(parent, JoinPath(parentPath), i, 1); err != nil {
+				return err
+			}
+		} else if _, exists := parent.Data[last.Elem]; exists {
+			s.detach(parent, last.Elem, JoinPath(path))
+			s.renameTransformed(JoinPath(path), "")
+		}
+	}
+	return s.setValue(JoinPath(path), val, file)
+}
+
+// setValue flattens val under key and stores it.
+func (s *Storage) setValue(key string, val any, file int8) error {
+	if t, ok := val.(patchTree); ok {
+		return s.setTree(key, t, file)
+	}
+	m := make(map[string]any)
+	patchFlattener.FlattenValue(key, val, m)
+	for _, k := range util.OrderedMapKeys(m) {
+		if k == "" { // an empty document
+			continue
+		}
+		if err := s.setAny(k, m[k], file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchTree is the value of a "move" or "copy" operation: the values
+// of the source by their key relative to it, so that sparse arrays keep
+// their indices.
+type patchTree map[string]ValueInfo
+
+// subtree returns the values of the node n, whose flattened key is key.
+func (s *Storage) subtree(n *treeNode, key string) patchTree {
+	t := make(patchTree)
+	var scratch []string
+	s.walk(n, key, &scratch, func(k string, v ValueInfo) bool {
+		rel := k[len(key):]
+		if key == "" && rel[0] != '[' {
+			rel = "." + rel
+		}
+		t[rel] = v
+		return true
+	})
+	return t
+}
+
+// setTree stores the values of t under key, attributed to file.
+func (s *Storage) setTree(key string, t patchTree, file int8) error {
+	for _, rel := range util.OrderedMapKeys(t) {
+		k := key + rel
+		if key == "" {
+			k = strings.TrimPrefix(rel, ".")
+		}
+		if k == "" { // an empty document
+			continue
+		}
+		path, err := SplitPath(k)
+		if err != nil {
+			return err
+		}
+		v := t[rel]
+		v.File = file
+		if err = s.set(k, path, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchRemove removes the value at path. An array element is removed
+// by shifting the following ones, and a container left without
+// children becomes an empty map or slice attributed to file.
+func (s *Storage) patchRemove(path []Path, file int8) error {
+	if len(path) == 0 {
+		s.dropRoot()
+		s.transformed = nil
+		return nil
+	}
+	parentPath, last := path[:len(path)-1], path[len(path)-1]
+	parent, ok := s.mutableAt(parentPath)
+	if !ok || parent.isLeaf() || parent.Type != last.Type || parent.Data[last.Elem] == nil {
+		return errNotExist(path)
+	}
+	s.detach(parent, last.Elem, JoinPath(path))
+	s.renameTransformed(JoinPath(path), "")
+	if last.Type == PathTypeIndex {
+		i, err := parseIndex(last.Elem)
+		if err != nil {
+			return err
+		}
+		if err = s.shiftArray(parent, JoinPath(parentPath), i+1, -1); err != nil {
+			return err
+		}
+	}
+	if len(parent.Data) > 0 {
+		return nil
+	}
+	if parent == s.root {
+		s.dropRoot()
+		return nil
+	}
+	marker := EmptyMap
+	if parent.Type == PathTypeIndex {
+		marker = EmptySlice
+	}
+	parent.Data = nil
+	parent.Value, _ = newValueInfo(marker, file)
+	if s.work != nil { // the container is a leaf now
+		s.work.removed = append(s.work.removed, removedNode{JoinPath(parentPath), parent})
+	}
+	return nil
+}
+
+// shiftArray moves the elements of the array node n, whose flattened
+// key is key, from index from onwards by delta positions, which is
+// either 1 or -1.
+func (s *Storage) shiftArray(n *treeNode, key string, from, delta int) error {
+	var indices []int
+	for elem := range n.Data {
+		i, err := parseIndex(elem)
+		if err != nil {
+			return err
+		}
+		if i >= from {
+			indices = append(indices, i)
+		}
+	}
+	slices.Sort(indices)
+	if delta > 0 {
+		slices.Reverse(indices)
+	}
+	for _, i := range indices {
+		oldElem, newElem := strconv.Itoa(i), strconv.Itoa(i+delta)
+		oldKey, newKey := childKey(key, PathTypeIndex, oldElem), childKey(key, PathTypeIndex, newElem)
+		c := n.Data[oldElem]
+		s.detach(n, oldElem, oldKey)
+		n.Data[s.internString(newElem)] = c
+		if s.work != nil {
+			s.work.moved = append(s.work.moved, newKey)
+		}
+		s.renameTransformed(oldKey, newKey)
+	}
+	return nil
+}
+
+// renameTransformed moves the transformer records of key and its
+// descendants to another key, or drops them if to is empty.
+func (s *Storage) renameTransformed(from, to string) {
+	for key, names := range s.transformed {
+		rest, ok := strings.CutPrefix(key, from)
+		if !ok || rest != "" && rest[0] != '.' && rest[0] != '[' {
+			continue
+		}
+		delete(s.transformed, key)
+		if to != "" {
+			s.transformed[to+rest] = names
+		}
+	}
+}
+
+// patchTest checks that the value at path equals val.
+func (s *Storage) patchTest(path []Path, val any) error {
+	n, ok := s.nodeAt(path)
+	if !ok {
+		return errNotExist(path)
+	}
+	key := JoinPath(path)
+	want := make(map[string]any)
+	patchFlattener.FlattenValue(key, val, want)
+	count, equal := 0, true
+	var scratch []string
+	s.walk(n, key, &scratch, func(k string, v ValueInfo) bool {
+		count++
+		w, ok := want[k]
+		equal = ok && sameValue(v, w)
+		return equal
+	})
+	if !equal || count != len(want) {
+		return util.FormatError(nil, "test failed: value at %q differs", JoinPointer(path))
+	}
+	return nil
+}
+
+// sameValue reports whether a stored value equals a flattened patch
+// value. Numbers compare by value, and strings by their text.
+func sameValue(v ValueInfo, val any) bool {
+	w, err := newValueInfo(val, v.File)
+	if err != nil {
+		return false
+	}
+	if v.Kind == KindString && !w.IsEmpty() {
+		return v.Value == w.Value
+	}
+	if isNumber(v.Kind) && isNumber(w.Kind) {
+		x, err1 := strconv.ParseFloat(v.Value, 64)
+		y, err2 := strconv.ParseFloat(w.Value, 64)
+		return err1 == nil && err2 == nil && x == y
+	}
+	return v.Kind == w.Kind && v.Value == w.Value
+}
+
+// isNumber reports whether k is a numeric kind.
+func isNumber(k Kind) bool {
+	return k == KindInt || k == KindUint || k == KindFloat
+}
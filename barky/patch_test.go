@@ -0,0 +1,257 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-spring/spring-base/testing/assert"
+	"github.com/go-spring/spring-base/util"
+)
+
+// newJSONStorage creates a Storage holding a JSON document.
+func newJSONStorage(t *testing.T, doc string) *Storage {
+	t.Helper()
+	v, err := decodePatchValue([]byte(doc))
+	assert.That(t, err).Nil()
+	s := NewStorage()
+	file := s.AddFile("base.json")
+	assert.That(t, s.setValue("", v, file)).Nil()
+	return s
+}
+
+// jsonOf returns the JSON encoding of a Storage.
+func jsonOf(t *testing.T, s *Storage) string {
+	t.Helper()
+	b, err := s.MarshalJSON()
+	assert.That(t, err).Nil()
+	return string(b)
+}
+
+// assertIndexed checks that the key index of s matches its tree.
+func assertIndexed(t *testing.T, s *Storage) {
+	t.Helper()
+	index := s.index
+	s.reindex()
+	assert.Map(t, index).Equal(s.index)
+}
+
+func TestApplyPatch(t *testing.T) {
+	const doc = `{"db":{"hosts":["a","b","c"],"port":5432},"ports":{"80":"http"},"tags":[]}`
+
+	t.Run("operations", func(t *testing.T) {
+		for _, c := range []struct {
+			patch  string
+			result string
+		}{
+			{
+				patch:  `[{"op":"add","path":"/db/hosts/1","value":"x"}]`,
+				result: `{"db":{"hosts":["a","x","b","c"],"port":5432},"ports":{"80":"http"},"tags":[]}`,
+			},
+			{
+				patch:  `[{"op":"add","path":"/db/hosts/-","value":"x"},{"op":"add","path":"/tags/-","value":{"k":true}}]`,
+				result: `{"db":{"hosts":["a","b","c","x"],"port":5432},"ports":{"80":"http"},"tags":[{"k":true}]}`,
+			},
+			{
+				patch:  `[{"op":"add","path":"/db","value":{"user":"root"}},{"op":"add","path":"/ports/443","value":"https"}]`,
+				result: `{"db":{"user":"root"},"ports":{"443":"https","80":"http"},"tags":[]}`,
+			},
+			{
+				patch:  `[{"op":"remove","path":"/db/hosts/0"},{"op":"remove","path":"/ports/80"}]`,
+				result: `{"db":{"hosts":["b","c"],"port":5432},"ports":{},"tags":[]}`,
+			},
+			{
+				patch:  `[{"op":"replace","path":"/db/hosts/1","value":null},{"op":"replace","path":"/tags","value":["t"]}]`,
+				result: `{"db":{"hosts":["a",null,"c"],"port":5432},"ports":{"80":"http"},"tags":["t"]}`,
+			},
+			{
+				patch:  `[{"op":"move","from":"/db/hosts/0","path":"/db/hosts/-"},{"op":"move","from":"/ports","path":"/db/ports"}]`,
+				result: `{"db":{"hosts":["b","c","a"],"port":5432,"ports":{"80":"http"}},"tags":[]}`,
+			},
+			{
+				patch:  `[{"op":"copy","from":"/db/port","path":"/port"},{"op":"copy","from":"/db/hosts","path":"/tags/0"}]`,
+				result: `{"db":{"hosts":["a","b","c"],"port":5432},"port":5432,"ports":{"80":"http"},"tags":[["a","b","c"]]}`,
+			},
+			{
+				patch:  `[{"op":"test","path":"/db","value":{"port":5432.0,"hosts":["a","b","c"]}},{"op":"test","path":"/tags","value":[]}]`,
+				result: doc,
+			},
+			{
+				patch:  `[{"op":"move","from":"/db","path":"/db"},{"op":"replace","path":"","value":{"a":[]}}]`,
+				result: `{"a":[]}`,
+			},
+			{
+				patch:  `[{"op":"remove","path":""},{"op":"add","path":"/a~1b","value":1}]`,
+				result: `{"a/b":1}`,
+			},
+		} {
+			s := newJSONStorage(t, doc)
+			assert.That(t, s.ApplyPatch("patch", []byte(c.patch))).Nil()
+			assert.That(t, jsonOf(t, s)).Equal(c.result)
+			assertIndexed(t, s)
+		}
+	})
+
+	t.Run("sparse arrays", func(t *testing.T) {
+		s := NewStorage()
+		assert.That(t, s.Set("list[0]", "a", 0)).Nil()
+		assert.That(t, s.Set("list[5].name", "b", 0)).Nil()
+		patch := `[{"op":"copy","from":"/list","path":"/copy"},{"op":"move","from":"/list","path":"/moved"}]`
+		assert.That(t, s.ApplyPatch("p", []byte(patch))).Nil()
+		assert.That(t, s.Keys()).Equal([]string{"copy[0]", "copy[5].name", "moved[0]", "moved[5].name"})
+		assertIndexed(t, s)
+	})
+
+	t.Run("provenance", func(t *testing.T) {
+		s := newJSONStorage(t, doc)
+		assert.That(t, s.ApplyPatch("patch-1", []byte(`[{"op":"move","from":"/db/port","path":"/port"}]`))).Nil()
+		v, _ := s.Lookup("port")
+		assert.That(t, s.fileName(v.File)).Equal("patch-1")
+		v, _ = s.Lookup("db.hosts[0]")
+		assert.That(t, s.fileName(v.File)).Equal("base.json")
+	})
+
+	t.Run("string values", func(t *testing.T) {
+		s := NewStorage()
+		assert.That(t, s.Set("db.port", "5432", 0)).Nil()
+		assert.That(t, s.ApplyPatch("p", []byte(`[{"op":"test","path":"/db/port","value":5432}]`))).Nil()
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, c := range []struct {
+			patch string
+			err   string
+		}{
+			{`{}`, `invalid JSON patch p: json: cannot unmarshal object`},
+			{`[{"op":"add","value":1}]`, `apply patch p: operation 0 \(add \): missing path`},
+			{`[{"op":"add","path":"/a"}]`, `operation 0 \(add /a\): missing value`},
+			{`[{"op":"move","path":"/a"}]`, `operation 0 \(move /a\): missing from`},
+			{`[{"op":"merge","path":"/a"}]`, `operation 0 \(merge /a\): unknown operation "merge"`},
+			{`[{"op":"add","path":"a","value":1}]`, `JSON pointer must start with '/'`},
+			{`[{"op":"add","path":"/x/y","value":1}]`, `operation 0 \(add /x/y\): path "/x" does not exist`},
+			{`[{"op":"add","path":"/db/hosts/5","value":1}]`, `index 5 out of range \[0, 3\]`},
+			{`[{"op":"add","path":"/db/port/x","value":1}]`, `property conflict at path db.port.x: existing value \(from base.json\) vs map \(from p\)`},
+			{`[{"op":"add","path":"/db/hosts/x","value":1}]`, `property conflict at path db.hosts.x: existing array \(from base.json\) vs map \(from p\)`},
+			{`[{"op":"add","path":"","value":1}]`, `the document must be a map or an array`},
+			{`[{"op":"remove","path":"/db/user"}]`, `operation 0 \(remove /db/user\): path "/db/user" does not exist`},
+			{`[{"op":"replace","path":"/db/user","value":1}]`, `path "/db/user" does not exist`},
+			{`[{"op":"copy","from":"/x","path":"/y"}]`, `path "/x" does not exist`},
+			{`[{"op":"move","from":"/db","path":"/db/x"}]`, `cannot move /db into itself`},
+			{`[{"op":"test","path":"/db/port","value":"5432"}]`, `test failed: value at "/db/port" differs`},
+			{`[{"op":"test","path":"/db/hosts","value":["a","b"]}]`, `test failed: value at "/db/hosts" differs`},
+			{`[{"op":"test","path":"/tags","value":{}}]`, `test failed`},
+			{`[{"op":"add","path":"/x","value":1},{"op":"test","path":"/x","value":2}]`, `operation 1 \(test /x\): test failed`},
+		} {
+			s := newJSONStorage(t, doc)
+			err := s.ApplyPatch("p", []byte(c.patch))
+			assert.Error(t, err).Matches(c.err)
+			// Nothing is applied if an operation fails.
+			assert.That(t, jsonOf(t, s)).Equal(doc)
+			_, ok := s.RawFile()["p"]
+			assert.That(t, ok).False()
+		}
+
+		// A failed patch doesn't keep the segments it interned.
+		s := newJSONStorage(t, doc)
+		n := len(s.intern)
+		err := s.ApplyPatch("p", []byte(`[{"op":"add","path":"/new","value":{"key":1}},{"op":"remove","path":"/x"}]`))
+		assert.Error(t, err).Matches(`path "/x" does not exist`)
+		assert.That(t, len(s.intern)).Equal(n)
+
+		s = newJSONStorage(t, doc)
+		err = s.ApplyPatch("p", []byte(`[{"op":"add","path":"/db/port/x","value":1}]`))
+		assert.That(t, errors.Is(err, ErrConflict)).True()
+		var pe *PatchError
+		assert.That(t, errors.As(err, &pe)).True()
+		assert.That(t, pe.Index).Equal(0)
+		assert.That(t, pe.Path).Equal("/db/port/x")
+
+		// Indices that don't fit in an int are rejected, not wrapped.
+		for _, patch := range []string{
+			`[{"op":"add","path":"/a/-","value":1}]`,
+			`[{"op":"add","path":"/a/0","value":1}]`,
+			`[{"op":"remove","path":"/a/9223372036854775807"}]`,
+		} {
+			s = NewStorage()
+			assert.That(t, s.Set("a[9223372036854775807]", "x", 0)).Nil()
+			err = s.ApplyPatch("p", []byte(patch))
+			assert.Error(t, err).Matches(`index 9223372036854775807 is out of range`)
+		}
+	})
+
+	t.Run("transformers", func(t *testing.T) {
+		s := NewStorage()
+		assert.That(t, s.AddTransformer("trim", "list[*]", TrimSpace)).Nil()
+		assert.That(t, s.Set("list[0]", " a ", 0)).Nil()
+		assert.That(t, s.Set("list[1]", "b", 0)).Nil()
+		patch := `[{"op":"add","path":"/list/0","value":" x "},{"op":"remove","path":"/list/2"}]`
+		assert.That(t, s.ApplyPatch("p", []byte(patch))).Nil()
+		assert.That(t, s.Data()).Equal(map[string]string{"list[0]": "x", "list[1]": "a"})
+		assert.That(t, s.TransformedBy("list[0]")).Equal([]string{"trim"})
+		assert.That(t, s.TransformedBy("list[1]")).Equal([]string{"trim"})
+		assert.That(t, s.TransformedBy("list[2]")).Nil()
+	})
+
+	t.Run("frozen", func(t *testing.T) {
+		s := NewBuilder().Build()
+		err := s.ApplyPatch("p", []byte(`[]`))
+		assert.That(t, errors.Is(err, util.ErrForbiddenMethod)).True()
+		err = s.ApplyMergePatch("p", []byte(`{}`))
+		assert.That(t, errors.Is(err, util.ErrForbiddenMethod)).True()
+	})
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	const doc = `{"db":{"hosts":["a","b"],"port":5432,"tls":{}},"name":"app"}`
+
+	for _, c := range []struct {
+		patch  string
+		result string
+	}{
+		{`{}`, doc},
+		{`{"name":null,"x":null}`, `{"db":{"hosts":["a","b"],"port":5432,"tls":{}}}`},
+		{`{"db":{"hosts":["c"],"port":null,"tls":{"on":true}}}`, `{"db":{"hosts":["c"],"tls":{"on":true}},"name":"app"}`},
+		{`{"db":{"hosts":{"a":1}},"name":{"first":"x"}}`, `{"db":{"hosts":{"a":1},"port":5432,"tls":{}},"name":{"first":"x"}}`},
+		{`{"db":"none","new":{"a":{}}}`, `{"db":"none","name":"app","new":{"a":{}}}`},
+		{`{"db":{"hosts":null,"port":null,"tls":null}}`, `{"db":{},"name":"app"}`},
+		{`["x"]`, `["x"]`},
+	} {
+		s := newJSONStorage(t, doc)
+		assert.That(t, s.ApplyMergePatch("merge", []byte(c.patch))).Nil()
+		assert.That(t, jsonOf(t, s)).Equal(c.result)
+		assertIndexed(t, s)
+	}
+
+	s := newJSONStorage(t, doc)
+	assert.That(t, s.ApplyMergePatch("merge", []byte(`{"db":{"port":6543}}`))).Nil()
+	v, _ := s.Lookup("db.port")
+	assert.That(t, s.fileName(v.File)).Equal("merge")
+	v, _ = s.Lookup("name")
+	assert.That(t, s.fileName(v.File)).Equal("base.json")
+
+	for patch, msg := range map[string]string{
+		`{`:                `invalid merge patch merge: invalid value: unexpected EOF`,
+		`1`:                `apply merge patch merge: the document must be a map or an array`,
+		`{"db":{"a.b":1}}`: `key segment "a.b" cannot contain`,
+	} {
+		s = newJSONStorage(t, doc)
+		err := s.ApplyMergePatch("merge", []byte(patch))
+		assert.Error(t, err).Matches(msg)
+		assert.That(t, jsonOf(t, s)).Equal(doc)
+	}
+}
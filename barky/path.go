@@ -17,6 +17,9 @@
 package barky
 
 import (
+	"cmp"
+	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -81,15 +84,30 @@ func JoinPath(path []Path) string {
 //   - Keys must be non-empty strings without spaces.
 //   - Indices must be unsigned integers (no sign, no decimal).
 //   - Empty maps/slices are not special-cased here.
-//   - Returns an error if the key is malformed (e.g. unbalanced brackets,
-//     unexpected characters, or empty keys if disallowed).
-func SplitPath(key string) (_ []Path, err error) {
+//   - Returns a *PathSyntaxError if the key is malformed (e.g. unbalanced
+//     brackets, unexpected characters, or empty keys if disallowed).
+func SplitPath(key string) ([]Path, error) {
+	var path []Path
+	err := scanPath(key, func(p Path) bool {
+		path = append(path, p)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+// scanPath parses a hierarchical key like SplitPath, but passes each
+// segment to yield instead of collecting them, so that lookups don't
+// allocate. Segments may be yielded before an error is detected later
+// in the key. Scanning stops without error if yield returns false.
+func scanPath(key string, yield func(Path) bool) error {
 	if key == "" {
-		return nil, util.FormatError(nil, "invalid key: empty string")
+		return &PathSyntaxError{Key: key, Reason: "empty string"}
 	}
 
 	var (
-		path        []Path
 		lastPos     int  // start index of current segment
 		lastChar    rune // previous rune seen (0 initial)
 		openBracket bool // whether we're inside '[' ... ']'
@@ -98,31 +116,37 @@ func SplitPath(key string) (_ []Path, err error) {
 	for i, c := range key {
 		switch c {
 		case ' ':
-			return nil, util.FormatError(nil, "invalid key %q: contains space at pos %d", key, i)
+			return &PathSyntaxError{Key: key, Pos: i, Reason: "contains space"}
 		case '.':
 			if openBracket {
-				return nil, util.FormatError(nil, "invalid key %q at pos %d: '.' not allowed inside brackets", key, i)
+				return &PathSyntaxError{Key: key, Pos: i, Reason: "'.' not allowed inside brackets"}
 			}
 			if lastChar == '.' {
-				return nil, util.FormatError(nil, "invalid key %q at pos %d: empty key between dots", key, i)
+				return &PathSyntaxError{Key: key, Pos: i, Reason: "empty key between dots"}
 			}
 			if lastChar != ']' {
-				if path, err = appendKey(path, key[lastPos:i]); err != nil {
-					return nil, util.FormatError(err, "invalid key %q at pos %d", key, lastPos)
+				if err := checkKey(key[lastPos:i]); err != nil {
+					return &PathSyntaxError{Key: key, Pos: lastPos, Reason: err.Error()}
+				}
+				if !yield(Path{Type: PathTypeKey, Elem: key[lastPos:i]}) {
+					return nil
 				}
 			}
 			lastPos = i + 1
 			lastChar = '.'
 		case '[':
 			if openBracket {
-				return nil, util.FormatError(nil, "invalid key %q at pos %d: nested '['", key, i)
+				return &PathSyntaxError{Key: key, Pos: i, Reason: "nested '['"}
 			}
 			if lastChar == '.' {
-				return nil, util.FormatError(nil, "invalid key %q at pos %d: '[' cannot directly follow '.'", key, i)
+				return &PathSyntaxError{Key: key, Pos: i, Reason: "'[' cannot directly follow '.'"}
 			}
 			if i > 0 && lastChar != ']' {
-				if path, err = appendKey(path, key[lastPos:i]); err != nil {
-					return nil, util.FormatError(err, "invalid key %q at pos %d", key, lastPos)
+				if err := checkKey(key[lastPos:i]); err != nil {
+					return &PathSyntaxError{Key: key, Pos: lastPos, Reason: err.Error()}
+				}
+				if !yield(Path{Type: PathTypeKey, Elem: key[lastPos:i]}) {
+					return nil
 				}
 			}
 			openBracket = true
@@ -130,13 +154,16 @@ func SplitPath(key string) (_ []Path, err error) {
 			lastChar = '['
 		case ']':
 			if !openBracket {
-				return nil, util.FormatError(nil, "invalid key %q at pos %d: ']' without matching '['", key, i)
+				return &PathSyntaxError{Key: key, Pos: i, Reason: "']' without matching '['"}
 			}
 			if lastPos == i {
-				return nil, util.FormatError(nil, "invalid key %q at pos %d: empty index", key, lastPos)
+				return &PathSyntaxError{Key: key, Pos: lastPos, Reason: "empty index"}
 			}
-			if path, err = appendIndex(path, key[lastPos:i]); err != nil {
-				return nil, util.FormatError(err, "invalid key %q at pos %d", key, lastPos)
+			if err := checkIndex(key[lastPos:i]); err != nil {
+				return &PathSyntaxError{Key: key, Pos: lastPos, Reason: err.Error()}
+			}
+			if !yield(Path{Type: PathTypeIndex, Elem: key[lastPos:i]}) {
+				return nil
 			}
 			openBracket = false
 			lastPos = i + 1
@@ -144,45 +171,134 @@ func SplitPath(key string) (_ []Path, err error) {
 		default:
 			// if previous char was ']' and now we see other char that's not '.' or '[' it's invalid:
 			if lastChar == ']' {
-				return nil, util.FormatError(nil, "invalid key %q at pos %d: unexpected character %q after ']'", key, i, c)
+				return &PathSyntaxError{Key: key, Pos: i, Reason: fmt.Sprintf("unexpected character %q after ']'", c)}
 			}
 			lastChar = c
 		}
 	}
 
 	if openBracket {
-		return nil, util.FormatError(nil, "invalid key %q at pos %d: unclosed '['", key, lastPos-1)
+		return &PathSyntaxError{Key: key, Pos: lastPos - 1, Reason: "unclosed '['"}
 	}
 	if lastChar == '.' {
-		return nil, util.FormatError(nil, "invalid key %q at pos %d: ends with '.'", key, len(key)-1)
+		return &PathSyntaxError{Key: key, Pos: len(key) - 1, Reason: "ends with '.'"}
 	}
 	if lastChar != ']' {
-		if path, err = appendKey(path, key[lastPos:]); err != nil {
-			return nil, util.FormatError(err, "invalid key %q at pos %d", key, lastPos)
+		if err := checkKey(key[lastPos:]); err != nil {
+			return &PathSyntaxError{Key: key, Pos: lastPos, Reason: err.Error()}
+		}
+		if !yield(Path{Type: PathTypeKey, Elem: key[lastPos:]}) {
+			return nil
 		}
 	}
 
-	return path, nil
+	return nil
 }
 
-// appendKey validates and appends a key segment.
-func appendKey(path []Path, s string) ([]Path, error) {
+// checkKey validates a key segment.
+func checkKey(s string) error {
 	if s == "" {
-		return nil, util.FormatError(nil, "empty key segment")
+		return util.FormatError(nil, "empty key segment")
 	}
 	if strings.ContainsRune(s, ' ') {
-		return nil, util.FormatError(nil, "key segment %q contains space", s)
+		return util.FormatError(nil, "key segment %q contains space", s)
 	}
-	return append(path, Path{Type: PathTypeKey, Elem: s}), nil
+	return nil
 }
 
-// appendIndex validates and appends an index segment.
-func appendIndex(path []Path, s string) ([]Path, error) {
+// checkIndex validates an index segment.
+func checkIndex(s string) error {
 	if s == "" {
-		return nil, util.FormatError(nil, "empty index")
+		return util.FormatError(nil, "empty index")
 	}
 	if _, err := strconv.ParseUint(s, 10, 64); err != nil {
-		return nil, util.FormatError(nil, "index must be an unsigned integer (got %q)", s)
+		return util.FormatError(nil, "index must be an unsigned integer (got %q)", s)
+	}
+	return nil
+}
+
+// appendKey validates and appends a key segment.
+func appendKey(path []Path, s string) ([]Path, error) {
+	if err := checkKey(s); err != nil {
+		return nil, err
+	}
+	return append(path, Path{Type: PathTypeKey, Elem: s}), nil
+}
+
+// appendIndex validates and appends an index segment.
+func appendIndex(path []Path, s string) ([]Path, error) {
+	if err := checkIndex(s); err != nil {
+		return nil, err
 	}
 	return append(path, Path{Type: PathTypeIndex, Elem: s}), nil
 }
+
+// ParentPath returns the path without its last segment, e.g. the parent
+// of "a.b[0].c" is "a.b[0]". The parent of a single-segment path is the
+// empty (root) path, and the parent of an empty path is nil.
+func ParentPath(path []Path) []Path {
+	if len(path) == 0 {
+		return nil
+	}
+	return slices.Clip(path[:len(path)-1])
+}
+
+// BasePath returns the last segment of the path, e.g. "c" for "a.b[0].c"
+// and the index "0" for "a.b[0]". It returns the zero Path for an
+// empty path.
+func BasePath(path []Path) Path {
+	if len(path) == 0 {
+		return Path{}
+	}
+	return path[len(path)-1]
+}
+
+// IsAncestor reports whether ancestor is a proper prefix of path, i.e.
+// path refers to a descendant of ancestor. The empty path is an ancestor
+// of every non-empty path.
+func IsAncestor(ancestor, path []Path) bool {
+	return len(ancestor) < len(path) && slices.Equal(ancestor, path[:len(ancestor)])
+}
+
+// Rel returns path relative to base, e.g. "b[0].c" for "a.b[0].c"
+// relative to "a". It returns an empty path if both are equal, and an
+// error if path is not base or a descendant of base.
+func Rel(base, path []Path) ([]Path, error) {
+	if len(base) > len(path) || !slices.Equal(base, path[:len(base)]) {
+		return nil, util.FormatError(nil, "path %s is not under %s", JoinPath(path), JoinPath(base))
+	}
+	return slices.Clone(path[len(base):]), nil
+}
+
+// Append returns a new path consisting of path followed by elems.
+// The original path is never modified.
+func Append(path []Path, elems ...Path) []Path {
+	r := make([]Path, 0, len(path)+len(elems))
+	return append(append(r, path...), elems...)
+}
+
+// Compare compares two paths segment by segment and returns -1, 0 or +1.
+// Index segments sort before key segments, keys compare lexicographically
+// and indices numerically, so "a[2]" sorts before "a[10]". A path sorts
+// before its descendants.
+func Compare(a, b []Path) int {
+	for i := range min(len(a), len(b)) {
+		x, y := a[i], b[i]
+		if x.Type != y.Type {
+			if x.Type == PathTypeIndex {
+				return -1
+			}
+			return 1
+		}
+		var c int
+		if x.Type == PathTypeIndex {
+			c = compareIndex(x.Elem, y.Elem)
+		} else {
+			c = strings.Compare(x.Elem, y.Elem)
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(a), len(b))
+}
@@ -17,6 +17,7 @@
 package barky
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/go-spring/spring-base/testing/assert"
@@ -40,7 +41,7 @@ func TestPath(t *testing.T) {
 		{
 			name: "space key",
 			key:  " ",
-			err:  "contains space at pos 0",
+			err:  "invalid key \" \" at pos 0: contains space",
 		},
 		{
 			name: "single dot",
@@ -372,3 +373,88 @@ func TestPath(t *testing.T) {
 		})
 	})
 }
+
+func TestPathHelpers(t *testing.T) {
+
+	mustSplit := func(key string) []Path {
+		if key == "" {
+			return []Path{}
+		}
+		path, err := SplitPath(key)
+		assert.That(t, err).Nil()
+		return path
+	}
+
+	t.Run("ParentPath", func(t *testing.T) {
+		assert.That(t, JoinPath(ParentPath(mustSplit("a.b[0].c")))).Equal("a.b[0]")
+		assert.That(t, JoinPath(ParentPath(mustSplit("a.b[0]")))).Equal("a.b")
+		assert.That(t, ParentPath(mustSplit("a"))).Equal([]Path{})
+		assert.That(t, ParentPath(nil)).Nil()
+
+		path := mustSplit("a.b")
+		parent := append(ParentPath(path), Path{PathTypeKey, "x"})
+		assert.That(t, JoinPath(path)).Equal("a.b")
+		assert.That(t, JoinPath(parent)).Equal("a.x")
+	})
+
+	t.Run("BasePath", func(t *testing.T) {
+		assert.That(t, BasePath(mustSplit("a.b[0].c"))).Equal(Path{PathTypeKey, "c"})
+		assert.That(t, BasePath(mustSplit("a.b[0]"))).Equal(Path{PathTypeIndex, "0"})
+		assert.That(t, BasePath(nil)).Equal(Path{})
+	})
+
+	t.Run("IsAncestor", func(t *testing.T) {
+		assert.That(t, IsAncestor(mustSplit("a"), mustSplit("a.b[0]"))).True()
+		assert.That(t, IsAncestor(mustSplit("a.b"), mustSplit("a.b[0]"))).True()
+		assert.That(t, IsAncestor(mustSplit(""), mustSplit("a"))).True()
+		assert.That(t, IsAncestor(mustSplit("a.b"), mustSplit("a.b"))).False()
+		assert.That(t, IsAncestor(mustSplit("a.b"), mustSplit("a.bc"))).False()
+		assert.That(t, IsAncestor(mustSplit("a[0]"), mustSplit("a.0"))).False()
+		assert.That(t, IsAncestor(mustSplit("a.b.c"), mustSplit("a.b"))).False()
+	})
+
+	t.Run("Rel", func(t *testing.T) {
+		rel, err := Rel(mustSplit("a"), mustSplit("a.b[0].c"))
+		assert.That(t, err).Nil()
+		assert.That(t, JoinPath(rel)).Equal("b[0].c")
+
+		rel, err = Rel(mustSplit("a.b"), mustSplit("a.b[0].c"))
+		assert.That(t, err).Nil()
+		assert.That(t, JoinPath(rel)).Equal("[0].c")
+
+		rel, err = Rel(mustSplit("a.b"), mustSplit("a.b"))
+		assert.That(t, err).Nil()
+		assert.That(t, rel).Equal([]Path{})
+
+		_, err = Rel(mustSplit("a.c"), mustSplit("a.b[0]"))
+		assert.Error(t, err).Matches(`path a.b\[0\] is not under a.c`)
+		_, err = Rel(mustSplit("a.b.c"), mustSplit("a.b"))
+		assert.Error(t, err).Matches("path a.b is not under a.b.c")
+	})
+
+	t.Run("Append", func(t *testing.T) {
+		base := mustSplit("a.b")[:1]
+		p1 := Append(base, Path{PathTypeIndex, "1"})
+		p2 := Append(base, Path{PathTypeKey, "x"}, Path{PathTypeKey, "y"})
+		assert.That(t, JoinPath(p1)).Equal("a[1]")
+		assert.That(t, JoinPath(p2)).Equal("a.x.y")
+		assert.That(t, JoinPath(base)).Equal("a")
+	})
+
+	t.Run("Compare", func(t *testing.T) {
+		keys := []string{"a.b", "a[10]", "a", "a[2]", "b", "a.b.c", "a.a", "a[2].x"}
+		paths := make([][]Path, len(keys))
+		for i, k := range keys {
+			paths[i] = mustSplit(k)
+		}
+		slices.SortFunc(paths, Compare)
+		var sorted []string
+		for _, p := range paths {
+			sorted = append(sorted, JoinPath(p))
+		}
+		assert.That(t, sorted).Equal([]string{
+			"a", "a[2]", "a[2].x", "a[10]", "a.a", "a.b", "a.b.c", "b",
+		})
+		assert.That(t, Compare(mustSplit("a.b"), mustSplit("a.b"))).Equal(0)
+	})
+}
@@ -0,0 +1,193 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+)
+
+// patternSeg is a segment of a Pattern.
+type patternSeg struct {
+	Type PathType // Type of the matched segment, unused for deep wildcards.
+	Elem string   // Literal key or index, "" for wildcards.
+	Wild bool     // Whether the segment matches any key or index.
+	Deep bool     // Whether the segment matches any number of segments.
+}
+
+// Pattern is a compiled key pattern, such as "servers[*].password" or
+// "db.**". It uses the key syntax of SplitPath, with three wildcards:
+//
+//   - "*" in place of a key matches any single key segment.
+//   - "[*]" matches any single array index.
+//   - "**" in place of a key matches zero or more segments of any type.
+type Pattern struct {
+	pattern string
+	segs    []patternSeg
+}
+
+// CompilePattern parses a key pattern into a Pattern.
+// It returns a *PathSyntaxError if the pattern is malformed.
+func CompilePattern(pattern string) (*Pattern, error) {
+	if pattern == "" {
+		return nil, &PathSyntaxError{Key: pattern, Reason: "empty string"}
+	}
+	var (
+		segs []patternSeg
+		pos  int // start offset of the current part
+	)
+	for i, part := range strings.Split(pattern, ".") {
+		if i > 0 {
+			pos++
+		}
+		start := pos
+		pos += len(part)
+
+		name, rest := part, ""
+		if j := strings.IndexByte(part, '['); j >= 0 {
+			name, rest = part[:j], part[j:]
+		}
+		// Only the first part may start with an index, e.g. "[*].a".
+		if name != "" || i > 0 || rest == "" {
+			if j := strings.IndexByte(name, ']'); j >= 0 {
+				return nil, &PathSyntaxError{Key: pattern, Pos: start + j, Reason: "']' without matching '['"}
+			}
+			switch name {
+			case "*":
+				segs = append(segs, patternSeg{Type: PathTypeKey, Wild: true})
+			case "**":
+				segs = append(segs, patternSeg{Deep: true})
+			default:
+				if err := checkKey(name); err != nil {
+					return nil, &PathSyntaxError{Key: pattern, Pos: start, Reason: err.Error()}
+				}
+				segs = append(segs, patternSeg{Type: PathTypeKey, Elem: name})
+			}
+		}
+		for rest != "" {
+			at := pos - len(rest)
+			if rest[0] != '[' {
+				return nil, &PathSyntaxError{Key: pattern, Pos: at, Reason: fmt.Sprintf("unexpected character %q after ']'", rest[0])}
+			}
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, &PathSyntaxError{Key: pattern, Pos: at, Reason: "unclosed '['"}
+			}
+			if index := rest[1:end]; index == "*" {
+				segs = append(segs, patternSeg{Type: PathTypeIndex, Wild: true})
+			} else {
+				if err := checkIndex(index); err != nil {
+					return nil, &PathSyntaxError{Key: pattern, Pos: at + 1, Reason: err.Error()}
+				}
+				segs = append(segs, patternSeg{Type: PathTypeIndex, Elem: index})
+			}
+			rest = rest[end+1:]
+		}
+	}
+	return &Pattern{pattern: pattern, segs: segs}, nil
+}
+
+// MustCompilePattern is like CompilePattern but panics if the pattern
+// is malformed. It simplifies the initialization of global variables.
+func MustCompilePattern(pattern string) *Pattern {
+	p, err := CompilePattern(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// String returns the source text of the pattern.
+func (p *Pattern) String() string {
+	return p.pattern
+}
+
+// Match reports whether the flattened key matches the pattern.
+// Malformed keys never match.
+func (p *Pattern) Match(key string) bool {
+	path, err := SplitPath(key)
+	if err != nil {
+		return false
+	}
+	return p.MatchPath(path)
+}
+
+// MatchPath reports whether the parsed path matches the pattern.
+func (p *Pattern) MatchPath(path []Path) bool {
+	return matchSegs(p.segs, path)
+}
+
+// matchSegs matches path against the pattern segments.
+func matchSegs(segs []patternSeg, path []Path) bool {
+	for len(segs) > 0 {
+		s := segs[0]
+		if s.Deep {
+			for i := range len(path) + 1 {
+				if matchSegs(segs[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 || path[0].Type != s.Type || (!s.Wild && path[0].Elem != s.Elem) {
+			return false
+		}
+		segs, path = segs[1:], path[1:]
+	}
+	return len(path) == 0
+}
+
+// prefix returns the flattened key of the literal segments that
+// precede the first wildcard.
+func (p *Pattern) prefix() string {
+	var key string
+	for _, s := range p.segs {
+		if s.Wild || s.Deep {
+			break
+		}
+		key = childKey(key, s.Type, s.Elem)
+	}
+	return key
+}
+
+// specificity ranks patterns that match the same key: more literal
+// segments rank higher, and deep wildcards rank lower than single ones.
+func (p *Pattern) specificity() int {
+	n := 0
+	for _, s := range p.segs {
+		switch {
+		case s.Deep:
+			n--
+		case !s.Wild:
+			n += len(p.segs) + 1
+		}
+	}
+	return n
+}
+
+// Match returns an iterator over the leaf values whose keys match the
+// pattern, in tree order (see Walk).
+func (s *Storage) Match(p *Pattern) iter.Seq2[string, ValueInfo] {
+	return func(yield func(string, ValueInfo) bool) {
+		for key, v := range s.Walk(p.prefix()) {
+			if p.Match(key) && !yield(key, v) {
+				return
+			}
+		}
+	}
+}
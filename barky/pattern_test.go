@@ -0,0 +1,119 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/go-spring/spring-base/testing/assert"
+)
+
+func TestPattern(t *testing.T) {
+
+	t.Run("match", func(t *testing.T) {
+		for pattern, keys := range map[string]map[string]bool{
+			"a.b": {
+				"a.b": true, "a": false, "a.b.c": false, "a[0]": false,
+			},
+			"servers[*].port": {
+				"servers[0].port": true, "servers[12].port": true,
+				"servers.x.port": false, "servers[0].host": false, "servers[0]": false,
+			},
+			"*.password": {
+				"db.password": true, "password": false, "[0].password": false, "a.b.password": false,
+			},
+			"db.**": {
+				"db": true, "db.host": true, "db.hosts[0].name": true, "dbx": false,
+			},
+			"**.password": {
+				"password": true, "db.password": true, "a[0].b.password": true, "password.x": false,
+			},
+			"a.**.z": {
+				"a.z": true, "a.b.z": true, "a.b[1].c.z": true, "a.b": false,
+			},
+			"[*].name": {
+				"[3].name": true, "x.name": false,
+			},
+			"a[1]": {
+				"a[1]": true, "a[01]": false, "a[2]": false,
+			},
+		} {
+			p, err := CompilePattern(pattern)
+			assert.That(t, err).Nil()
+			assert.That(t, p.String()).Equal(pattern)
+			for key, want := range keys {
+				assert.That(t, p.Match(key)).Equal(want, pattern+" ~ "+key)
+			}
+			assert.That(t, p.Match("a..b")).False()
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for pattern, msg := range map[string]string{
+			"":      "invalid key: empty string",
+			"a..b":  `invalid key "a..b" at pos 2: empty key segment`,
+			"a[":    `invalid key "a\[" at pos 1: unclosed '\['`,
+			"a[x]":  `at pos 2: index must be an unsigned integer`,
+			"a[**]": `index must be an unsigned integer \(got "\*\*"\)`,
+			"a]":    `at pos 1: '\]' without matching '\['`,
+			"a[*]b": `at pos 4: unexpected character 'b' after '\]'`,
+			"a b.*": `contains space`,
+			".a":    `at pos 0: empty key segment`,
+			"a.[*]": `at pos 2: empty key segment`,
+		} {
+			_, err := CompilePattern(pattern)
+			assert.That(t, errors.Is(err, ErrInvalidPath)).True()
+			assert.Error(t, err).Matches(msg)
+		}
+		assert.Panic(t, func() { MustCompilePattern("a[") }, "unclosed")
+	})
+
+	t.Run("storage", func(t *testing.T) {
+		s := NewStorage()
+		for key, val := range map[string]string{
+			"db.password":          "secret",
+			"db.host":              "localhost",
+			"servers[0].password":  "p0",
+			"servers[10].password": "p10",
+			"servers[2].password":  "p2",
+			"servers[2].host":      "h2",
+			"password":             "root",
+		} {
+			assert.That(t, s.Set(key, val, 0)).Nil()
+		}
+
+		var keys []string
+		for key := range s.Match(MustCompilePattern("**.password")) {
+			keys = append(keys, key)
+		}
+		assert.That(t, keys).Equal([]string{
+			"db.password", "password", "servers[0].password", "servers[2].password", "servers[10].password",
+		})
+
+		m := maps.Collect(s.Match(MustCompilePattern("servers[*].host")))
+		assert.That(t, slices.Collect(maps.Keys(m))).Equal([]string{"servers[2].host"})
+
+		for range s.Match(MustCompilePattern("db.*")) {
+			break
+		}
+		m = maps.Collect(s.Match(MustCompilePattern("none.*")))
+		assert.That(t, len(m)).Equal(0)
+	})
+}
@@ -0,0 +1,251 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-spring/spring-base/util"
+)
+
+var (
+	pointerEscaper  = strings.NewReplacer("~", "~0", "/", "~1")
+	jsonPathEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+)
+
+// JoinPointer converts a path into an RFC 6901 JSON Pointer, e.g.
+// "db.hosts[0]" becomes "/db/hosts/0". '~' and '/' in keys are escaped
+// as "~0" and "~1", and the empty path becomes "", the whole document.
+// Use JoinPointer(SplitPath(key)) to convert a barky key.
+func JoinPointer(path []Path) string {
+	var sb strings.Builder
+	for _, p := range path {
+		sb.WriteByte('/')
+		sb.WriteString(pointerEscaper.Replace(p.Elem))
+	}
+	return sb.String()
+}
+
+// SplitPointer parses an RFC 6901 JSON Pointer into a path. Since a
+// pointer doesn't tell arrays from maps, segments made of digits only
+// are taken as indices, like in KV keys; as in RFC 6901, they may not
+// have leading zeros, e.g. "/a/01" is rejected. "" refers to the whole
+// document and gives an empty path. It returns a *PathSyntaxError if
+// the pointer is malformed, or if a segment cannot be a barky key
+// segment, e.g. because it contains '.' or is empty.
+func SplitPointer(ptr string) ([]Path, error) {
+	path := []Path{}
+	if ptr == "" {
+		return path, nil
+	}
+	if ptr[0] != '/' {
+		return nil, &PathSyntaxError{Key: ptr, Reason: "JSON pointer must start with '/'"}
+	}
+	pos := 1
+	for _, s := range strings.Split(ptr[1:], "/") {
+		start := pos
+		pos += len(s) + 1
+
+		var sb strings.Builder
+		for i := 0; i < len(s); i++ {
+			if s[i] != '~' {
+				sb.WriteByte(s[i])
+				continue
+			}
+			if i+1 == len(s) || (s[i+1] != '0' && s[i+1] != '1') {
+				return nil, &PathSyntaxError{Key: ptr, Pos: start + i, Reason: "'~' must be followed by '0' or '1'"}
+			}
+			if s[i+1] == '0' {
+				sb.WriteByte('~')
+			} else {
+				sb.WriteByte('/')
+			}
+			i++
+		}
+
+		var err error
+		if elem := sb.String(); elem != "" && strings.Trim(elem, "0123456789") == "" {
+			if len(elem) > 1 && elem[0] == '0' {
+				return nil, &PathSyntaxError{Key: ptr, Pos: start, Reason: fmt.Sprintf("index %q has a leading zero", elem)}
+			}
+			path, err = appendIndex(path, elem)
+		} else {
+			path, err = appendForeignKey(path, elem)
+		}
+		if err != nil {
+			return nil, &PathSyntaxError{Key: ptr, Pos: start, Reason: err.Error()}
+		}
+	}
+	return path, nil
+}
+
+// JoinJSONPath converts a path into a normalized JSONPath expression,
+// e.g. "db.hosts[0]" becomes "$.db.hosts[0]". Keys that are not valid
+// member names, such as "max-idle", use the bracket notation
+// "$['max-idle']". The empty path becomes "$".
+func JoinJSONPath(path []Path) string {
+	var sb strings.Builder
+	sb.WriteByte('$')
+	for _, p := range path {
+		switch {
+		case p.Type == PathTypeIndex:
+			sb.WriteByte('[')
+			sb.WriteString(p.Elem)
+			sb.WriteByte(']')
+		case isMemberName(p.Elem):
+			sb.WriteByte('.')
+			sb.WriteString(p.Elem)
+		default:
+			sb.WriteString("['")
+			sb.WriteString(jsonPathEscaper.Replace(p.Elem))
+			sb.WriteString("']")
+		}
+	}
+	return sb.String()
+}
+
+// SplitJSONPath parses a JSONPath expression that selects a single
+// value, made of member names ("$.db.hosts"), quoted names in brackets
+// ("$['db']" or `$["db"]`) and array indices ("$[0]"), into a path.
+// Wildcards, slices, filters and recursive descent are not supported.
+// It returns a *PathSyntaxError if the expression is malformed or not
+// supported, or if a name cannot be a barky key segment.
+func SplitJSONPath(expr string) ([]Path, error) {
+	if !strings.HasPrefix(expr, "$") {
+		return nil, &PathSyntaxError{Key: expr, Reason: "JSONPath must start with '$'"}
+	}
+	path := []Path{}
+	for pos := 1; pos < len(expr); {
+		start := pos
+		var err error
+		switch expr[pos] {
+		case '.':
+			pos++
+			for pos < len(expr) && expr[pos] != '.' && expr[pos] != '[' {
+				pos++
+			}
+			name := expr[start+1 : pos]
+			if !isMemberName(name) {
+				return nil, &PathSyntaxError{Key: expr, Pos: start + 1, Reason: fmt.Sprintf("unsupported member name %q", name)}
+			}
+			path, err = appendForeignKey(path, name)
+		case '[':
+			end := strings.IndexByte(expr[pos:], ']')
+			if end < 0 {
+				return nil, &PathSyntaxError{Key: expr, Pos: start, Reason: "unclosed '['"}
+			}
+			if q := expr[pos+1]; q == '\'' || q == '"' {
+				var name string
+				if name, pos, err = scanQuoted(expr, pos+1); err != nil {
+					return nil, &PathSyntaxError{Key: expr, Pos: start + 1, Reason: err.Error()}
+				}
+				if pos >= len(expr) || expr[pos] != ']' {
+					return nil, &PathSyntaxError{Key: expr, Pos: pos, Reason: "expected ']' after quoted name"}
+				}
+				pos++
+				path, err = appendForeignKey(path, name)
+			} else {
+				pos += end + 1
+				path, err = appendIndex(path, expr[start+1:pos-1])
+			}
+		default:
+			return nil, &PathSyntaxError{Key: expr, Pos: start, Reason: fmt.Sprintf("unexpected character %q", expr[start])}
+		}
+		if err != nil {
+			return nil, &PathSyntaxError{Key: expr, Pos: start + 1, Reason: err.Error()}
+		}
+	}
+	return path, nil
+}
+
+// scanQuoted scans the quoted string that starts at expr[pos] and
+// returns its unescaped content and the offset after the closing quote.
+func scanQuoted(expr string, pos int) (string, int, error) {
+	q := expr[pos]
+	var sb strings.Builder
+	for i := pos + 1; i < len(expr); i++ {
+		switch c := expr[i]; c {
+		case q:
+			return sb.String(), i + 1, nil
+		case '\\':
+			if i+1 == len(expr) {
+				return "", 0, util.FormatError(nil, "unterminated string")
+			}
+			i++
+			switch c = expr[i]; c {
+			case '\\', '/', '\'', '"':
+				sb.WriteByte(c)
+			case 'b':
+				sb.WriteByte('\b')
+			case 'f':
+				sb.WriteByte('\f')
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'u':
+				if i+5 > len(expr) {
+					return "", 0, util.FormatError(nil, "invalid escape sequence")
+				}
+				r, err := strconv.ParseUint(expr[i+1:i+5], 16, 32)
+				if err != nil {
+					return "", 0, util.FormatError(nil, "invalid escape sequence")
+				}
+				sb.WriteRune(rune(r))
+				i += 4
+			default:
+				return "", 0, util.FormatError(nil, "invalid escape sequence '\\%c'", c)
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", 0, util.FormatError(nil, "unterminated string")
+}
+
+// isMemberName reports whether s can be written in the JSONPath dot
+// notation: a letter, '_' or non-ASCII character, followed by letters,
+// digits, '_' or non-ASCII characters.
+func isMemberName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r >= utf8.RuneSelf:
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// appendForeignKey validates and appends a key segment that comes from
+// another syntax, rejecting the characters that have a meaning in barky
+// keys.
+func appendForeignKey(path []Path, s string) ([]Path, error) {
+	if strings.ContainsAny(s, ".[]") {
+		return nil, util.FormatError(nil, "key segment %q cannot contain '.', '[' or ']'", s)
+	}
+	return appendKey(path, s)
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-spring/spring-base/testing/assert"
+)
+
+func TestPointer(t *testing.T) {
+
+	t.Run("join", func(t *testing.T) {
+		assert.That(t, JoinPointer(nil)).Equal("")
+		path, err := SplitPath("db.hosts[0].addr")
+		assert.That(t, err).Nil()
+		assert.That(t, JoinPointer(path)).Equal("/db/hosts/0/addr")
+		path = []Path{{Type: PathTypeKey, Elem: "a/b~c"}, {Type: PathTypeIndex, Elem: "1"}}
+		assert.That(t, JoinPointer(path)).Equal("/a~1b~0c/1")
+	})
+
+	t.Run("split", func(t *testing.T) {
+		path, err := SplitPointer("")
+		assert.That(t, err).Nil()
+		assert.That(t, path).Equal([]Path{})
+
+		path, err = SplitPointer("/db/hosts/0/addr")
+		assert.That(t, err).Nil()
+		assert.That(t, JoinPath(path)).Equal("db.hosts[0].addr")
+
+		path, err = SplitPointer("/a/0/10")
+		assert.That(t, err).Nil()
+		assert.That(t, JoinPath(path)).Equal("a[0][10]")
+
+		path, err = SplitPointer("/a~1b~0c/~01")
+		assert.That(t, err).Nil()
+		assert.That(t, path).Equal([]Path{{Type: PathTypeKey, Elem: "a/b~c"}, {Type: PathTypeKey, Elem: "~1"}})
+	})
+
+	t.Run("split errors", func(t *testing.T) {
+		for ptr, msg := range map[string]string{
+			"a":       `invalid key "a" at pos 0: JSON pointer must start with '/'`,
+			"/a~2":    `invalid key "/a~2" at pos 2: '~' must be followed by '0' or '1'`,
+			"/a/~":    `invalid key "/a/~" at pos 3: '~' must be followed by '0' or '1'`,
+			"/a//b":   `invalid key "/a//b" at pos 3: empty key segment`,
+			"/a/":     `invalid key "/a/" at pos 3: empty key segment`,
+			"/a.b":    `invalid key "/a.b" at pos 1: key segment "a.b" cannot contain '.', '\[' or '\]'`,
+			"/a b/c":  `invalid key "/a b/c" at pos 1: key segment "a b" contains space`,
+			"/a/x[0]": `invalid key "/a/x\[0\]" at pos 3: key segment "x\[0\]" cannot contain`,
+			"/a/01":   `invalid key "/a/01" at pos 3: index "01" has a leading zero`,
+			"/00":     `invalid key "/00" at pos 1: index "00" has a leading zero`,
+		} {
+			_, err := SplitPointer(ptr)
+			assert.That(t, errors.Is(err, ErrInvalidPath)).True()
+			assert.Error(t, err).Matches(msg)
+		}
+	})
+}
+
+func TestJSONPath(t *testing.T) {
+
+	t.Run("join", func(t *testing.T) {
+		assert.That(t, JoinJSONPath(nil)).Equal("$")
+		path, err := SplitPath("db.hosts[0].max-idle[1][2]._x9")
+		assert.That(t, err).Nil()
+		assert.That(t, JoinJSONPath(path)).Equal("$.db.hosts[0]['max-idle'][1][2]._x9")
+		path = []Path{{Type: PathTypeKey, Elem: `it's\`}, {Type: PathTypeKey, Elem: "9a"}, {Type: PathTypeKey, Elem: "名字"}}
+		assert.That(t, JoinJSONPath(path)).Equal(`$['it\'s\\']['9a'].名字`)
+	})
+
+	t.Run("split", func(t *testing.T) {
+		path, err := SplitJSONPath("$")
+		assert.That(t, err).Nil()
+		assert.That(t, path).Equal([]Path{})
+
+		for expr, key := range map[string]string{
+			"$.db.hosts[0].addr":       "db.hosts[0].addr",
+			"$['db'][\"hosts\"][0][1]": "db.hosts[0][1]",
+			"$['max-idle'].名字":         "max-idle.名字",
+			`$['it\'s']`:               "it's",
+			`$["a\"\\\/A"]`:            `a"\/A`,
+			"$[0].a":                   "[0].a",
+		} {
+			path, err = SplitJSONPath(expr)
+			assert.That(t, err).Nil()
+			assert.That(t, JoinPath(path)).Equal(key)
+		}
+
+		path, err = SplitPath("db.hosts[0].max-idle")
+		assert.That(t, err).Nil()
+		r, err := SplitJSONPath(JoinJSONPath(path))
+		assert.That(t, err).Nil()
+		assert.That(t, r).Equal(path)
+	})
+
+	t.Run("split errors", func(t *testing.T) {
+		for expr, msg := range map[string]string{
+			"db":        `invalid key "db" at pos 0: JSONPath must start with '\$'`,
+			"$.":        `at pos 2: unsupported member name ""`,
+			"$..a":      `at pos 2: unsupported member name ""`,
+			"$.*":       `at pos 2: unsupported member name "\*"`,
+			"$.a-b":     `at pos 2: unsupported member name "a-b"`,
+			"$[*]":      `at pos 2: index must be an unsigned integer \(got "\*"\)`,
+			"$[-1]":     `at pos 2: index must be an unsigned integer`,
+			"$[0":       `at pos 1: unclosed '\['`,
+			"$['a]":     `at pos 2: unterminated string`,
+			"$['a'x]":   `at pos 5: expected '\]' after quoted name`,
+			`$['\x']`:   `at pos 2: invalid escape sequence '\\x'`,
+			`$['\u00']`: `at pos 2: invalid escape sequence`,
+			"$['a.b']":  `at pos 2: key segment "a.b" cannot contain`,
+			"$['a b']":  `at pos 2: key segment "a b" contains space`,
+			"$a":        `at pos 1: unexpected character 'a'`,
+		} {
+			_, err := SplitJSONPath(expr)
+			assert.That(t, errors.Is(err, ErrInvalidPath)).True()
+			assert.Error(t, err).Matches(msg)
+		}
+	})
+}
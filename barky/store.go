@@ -23,9 +23,10 @@
 // Key features include:
 //
 //   - Flattening: Nested maps, slices, and arrays can be converted into
-//     a flat map[string]string using dot notation for maps and index notation
-//     for arrays/slices. For example, {"db": {"hosts": ["a", "b"]}}
-//     becomes {"db.hosts[0]": "a", "db.hosts[1]": "b"}.
+//     a flat map using dot notation for maps and index notation for
+//     arrays/slices. For example, {"db": {"hosts": ["a", "b"]}} becomes
+//     {"db.hosts[0]": "a", "db.hosts[1]": "b"}. Nil values and empty
+//     containers are represented by typed markers, not sentinel strings.
 //
 //   - Path handling: The package defines a Path abstraction that represents
 //     hierarchical keys as a sequence of typed segments (map keys or array
@@ -57,105 +58,353 @@
 package barky
 
 import (
+	"iter"
 	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/go-spring/spring-base/util"
 )
 
 // treeNode represents a node in the hierarchical tree that models
-// the structure of keys in Storage. Each node corresponds to either
-// an object/map field or an array element, depending on its PathType.
+// the structure of keys in Storage. Each node is either a container,
+// whose children are object/map fields or array elements depending on
+// its PathType, or a leaf that holds a value.
 //
 // Internal invariant:
-//   - treeNode exists only to describe the structure (not to store values).
-//   - Leaf nodes are represented in Storage.data or Storage.empty instead
-//     of treeNode itself.
+//   - Container nodes have a non-nil Data map and no value.
+//   - Leaf nodes have a nil Data map and hold their value inline.
 type treeNode struct {
-	Type PathType
-	Data map[string]*treeNode
+	Type  PathType
+	Data  map[string]*treeNode
+	Value ValueInfo
 }
 
-// ValueInfo holds both the string value and the index of the file
+// isLeaf reports whether the node holds a value.
+func (n *treeNode) isLeaf() bool {
+	return n.Data == nil
+}
+
+// Kind describes what a stored value represents.
+type Kind int8
+
+const (
+	KindString     Kind = iota // A plain string value.
+	KindBool                   // A boolean value.
+	KindInt                    // A signed integer value.
+	KindUint                   // An unsigned integer value.
+	KindFloat                  // A floating-point value.
+	KindNil                    // A nil value.
+	KindEmptyMap               // An empty map.
+	KindEmptySlice             // An empty slice or array.
+)
+
+// ValueInfo holds the string value, its kind, and the index of the file
 // from which the value originated. This enables tracking of data provenance.
+// For nil values and empty containers, Value holds the textual form of
+// the corresponding Marker and Kind tells them apart from plain strings.
+// For booleans and numbers, Raw keeps the original typed value.
 type ValueInfo struct {
 	File  int8
 	Value string
+	Kind  Kind
+	Raw   any
 }
 
 // Storage manages hierarchical key/value data with structural validation.
 // It provides:
 //
-//   - A hierarchical tree (root) for detecting structural conflicts.
-//   - A flat map (data) for quick value lookups.
-//   - An empty map (empty) for representing empty containers like "[]" or "{}".
+//   - A hierarchical tree (root) for detecting structural conflicts,
+//     whose leaf nodes hold the values, including nil values and empty
+//     containers.
 //   - A file map for mapping file names to numeric indexes, allowing traceability.
 //
-// Invariants:
-//   - `root` stores only the tree structure (no leaf values).
-//   - `data` stores leaf key-value pairs.
-//   - `empty` stores leaf paths that represent empty arrays/maps or nil values.
+// Each path segment is stored once per tree node, and segment strings
+// are interned so that repeated names (e.g. "host", "0") share memory.
+// An index maps the flattened key of every map and array to its node,
+// so that a lookup finds the container of a key and then the key in it
+// instead of walking the tree; the keys of the containers created by
+// the same Set share one string. A Storage created by NewCompactStorage
+// doesn't keep the index, trading lookup speed for memory.
 type Storage struct {
-	root  *treeNode
-	data  map[string]ValueInfo
-	empty map[string]ValueInfo
-	file  map[string]int8
+	root   *treeNode
+	file   map[string]int8
+	intern map[string]string
+	index  map[string]*treeNode // flattened key -> node; nil if compact
+
+	transformers []transformer
+	transformed  map[string][]string // key -> names of the transformers that changed it
+
+	aliases []alias // deprecated key -> replacement, see AddAlias
+
+	frozen *frozenIndex // non-nil once frozen by a Builder
+	work   *workState   // non-nil in a working copy, see workingCopy
 }
 
 // NewStorage creates a new Storage instance.
 func NewStorage() *Storage {
+	s := NewCompactStorage()
+	s.index = make(map[string]*treeNode)
+	return s
+}
+
+// NewCompactStorage creates a Storage that doesn't index its keys. It
+// saves the index entry of every map and array, but each lookup walks
+// the tree, which is several times slower.
+func NewCompactStorage() *Storage {
 	return &Storage{
-		data:  make(map[string]ValueInfo),
-		empty: make(map[string]ValueInfo),
-		file:  make(map[string]int8),
+		file:   make(map[string]int8),
+		intern: make(map[string]string),
 	}
 }
 
-// RawData exposes the internal flattened key → ValueInfo mapping,
-// combining both data and empty containers if any exist.
-//
-// WARNING: This method leaks internal state and should be used
-// with caution (e.g., for debugging or low-level access).
+// RawData returns the flattened key → ValueInfo mapping, including
+// nil values and empty containers.
 func (s *Storage) RawData() map[string]ValueInfo {
-	if len(s.empty) > 0 {
-		m := make(map[string]ValueInfo)
-		maps.Copy(m, s.data)
-		maps.Copy(m, s.empty)
-		return m
+	m := make(map[string]ValueInfo)
+	for k, v := range s.All() {
+		m[k] = v
 	}
-	return s.data
+	return m
 }
 
 // Data returns a simplified flattened key → string value mapping,
-// omitting file index information.
+// omitting file index information, nil values and empty containers.
 func (s *Storage) Data() map[string]string {
 	m := make(map[string]string)
-	for k, v := range s.data {
-		m[k] = v.Value
+	for k, v := range s.All() {
+		if !v.IsEmpty() {
+			m[k] = v.Value
+		}
 	}
 	return m
 }
 
 // AddFile registers a file name in the Storage and assigns it
 // a unique int8 index if not already registered.
-// Returns the index assigned to the given file.
+// Returns the index assigned to the given file, or -1 if the file cannot
+// be registered; use TryAddFile to get the reason.
 func (s *Storage) AddFile(file string) int8 {
+	idx, err := s.TryAddFile(file)
+	if err != nil {
+		return -1
+	}
+	return idx
+}
+
+// TryAddFile is like AddFile, but returns an error if the Storage
+// already holds 128 files, or if it is frozen and the file is new.
+func (s *Storage) TryAddFile(file string) (int8, error) {
 	idx, ok := s.file[file]
 	if !ok {
+		if s.frozen != nil {
+			return 0, errFrozen("add file " + file)
+		}
+		if len(s.file) > math.MaxInt8 {
+			return 0, util.FormatError(nil, "add file %s error: too many files", file)
+		}
 		idx = int8(len(s.file))
 		s.file[file] = idx
 	}
-	return idx
+	return idx, nil
 }
 
 // RawFile exposes the internal file name → index mapping.
+// A frozen Storage returns a copy.
 func (s *Storage) RawFile() map[string]int8 {
+	if s.frozen != nil {
+		return maps.Clone(s.file)
+	}
 	return s.file
 }
 
-// Keys returns all flattened keys currently stored in Storage,
-// sorted lexicographically for consistent iteration.
+// Keys returns all flattened keys of stored values, excluding nil values
+// and empty containers, sorted lexicographically for consistent iteration.
 func (s *Storage) Keys() []string {
-	return util.OrderedMapKeys(s.data)
+	if s.frozen != nil {
+		return slices.Clone(s.frozen.keys)
+	}
+	keys := []string{}
+	for k, v := range s.All() {
+		if !v.IsEmpty() {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// All returns an iterator over all flattened keys and their values,
+// including empty containers. The iteration order is unspecified;
+// use Walk for a deterministic order.
+func (s *Storage) All() iter.Seq2[string, ValueInfo] {
+	return func(yield func(string, ValueInfo) bool) {
+		if s.root != nil {
+			s.all(s.root, "", yield)
+		}
+	}
+}
+
+// all visits the subtree rooted at n in unspecified order.
+// It returns false if the caller stopped the iteration.
+func (s *Storage) all(n *treeNode, key string, yield func(string, ValueInfo) bool) bool {
+	if n.isLeaf() {
+		return yield(key, n.Value)
+	}
+	for elem, c := range n.Data {
+		if !s.all(c, childKey(key, n.Type, elem), yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// Walk returns an iterator over the leaf values under the given prefix
+// in tree order: a depth-first traversal where map keys are visited in
+// lexicographic order and array elements in index order. An empty prefix
+// walks the whole Storage, and a prefix that refers to a leaf yields only
+// that leaf. Nothing is yielded if the prefix is invalid or absent.
+func (s *Storage) Walk(prefix string) iter.Seq2[string, ValueInfo] {
+	return func(yield func(string, ValueInfo) bool) {
+		if n, ok := s.lookup(prefix); ok {
+			var scratch []string
+			s.walk(n, prefix, &scratch, yield)
+		}
+	}
+}
+
+// walk visits the subtree rooted at n, whose flattened key is key.
+// The keys of each level are sorted in the tail of a scratch buffer
+// shared by the whole walk, so no slice is allocated per container.
+// It returns false if the caller stopped the iteration.
+func (s *Storage) walk(n *treeNode, key string, scratch *[]string, yield func(string, ValueInfo) bool) bool {
+	if n.isLeaf() {
+		return yield(key, n.Value)
+	}
+	start := len(*scratch)
+	for elem := range n.Data {
+		*scratch = append(*scratch, elem)
+	}
+	if n.Type == PathTypeIndex {
+		slices.SortFunc((*scratch)[start:], compareIndex)
+	} else {
+		slices.Sort((*scratch)[start:])
+	}
+	ok := true
+	for i := start; ok && i < start+len(n.Data); i++ {
+		// Deeper levels may grow the buffer, so index it afresh.
+		elem := (*scratch)[i]
+		ok = s.walk(n.Data[elem], childKey(key, n.Type, elem), scratch, yield)
+	}
+	*scratch = (*scratch)[:start]
+	return ok
+}
+
+// childKey appends a path element of the given type to a flattened key.
+func childKey(key string, typ PathType, elem string) string {
+	if typ == PathTypeIndex {
+		return key + "[" + elem + "]"
+	}
+	if key == "" {
+		return elem
+	}
+	return key + "." + elem
+}
+
+// compareIndex compares two array index strings numerically.
+func compareIndex(a, b string) int {
+	x, _ := strconv.ParseUint(a, 10, 64)
+	y, _ := strconv.ParseUint(b, 10, 64)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Children returns an iterator over the immediate child keys under the
+// given hierarchical path, in unspecified order. Unlike SubKeys, it does
+// not allocate a result slice. Nothing is yielded if the path is invalid,
+// absent, or refers to a leaf value or an empty container.
+func (s *Storage) Children(key string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		n, ok := s.lookup(key)
+		if !ok || n.isLeaf() {
+			return
+		}
+		for elem := range n.Data {
+			if !yield(elem) {
+				return
+			}
+		}
+	}
+}
+
+// lookup finds the tree node for the given key without allocating.
+// The empty key refers to the root.
+func (s *Storage) lookup(key string) (*treeNode, bool) {
+	if s.index != nil {
+		return s.indexed(key)
+	}
+	return s.scan(key)
+}
+
+// indexed is lookup for a Storage with an index: it finds the container
+// that holds key in the index, then key in the container.
+func (s *Storage) indexed(key string) (*treeNode, bool) {
+	if key == "" {
+		return s.root, s.root != nil
+	}
+	var (
+		parent, elem string
+		typ          PathType
+	)
+	if strings.HasSuffix(key, "]") {
+		i := strings.LastIndexByte(key, '[')
+		if i < 0 {
+			return nil, false
+		}
+		parent, elem, typ = key[:i], key[i+1:len(key)-1], PathTypeIndex
+	} else {
+		parent, elem, typ = "", key, PathTypeKey
+		if i := strings.LastIndexByte(key, '.'); i >= 0 {
+			parent, elem = key[:i], key[i+1:]
+		}
+	}
+	p := s.index[parent]
+	if p == nil || p.Type != typ {
+		return nil, false
+	}
+	n := p.Data[elem]
+	return n, n != nil
+}
+
+// scan is lookup for a Storage without an index.
+func (s *Storage) scan(key string) (*treeNode, bool) {
+	if s.root == nil {
+		return nil, false
+	}
+	if key == "" {
+		return s.root, true
+	}
+	n := s.root
+	err := scanPath(key, func(p Path) bool {
+		if n.isLeaf() || p.Type != n.Type {
+			n = nil
+			return false
+		}
+		n = n.Data[p.Elem]
+		return n != nil
+	})
+	if err != nil || n == nil {
+		return nil, false
+	}
+	return n, true
 }
 
 // SubKeys returns the immediate child keys under the given hierarchical path.
@@ -167,34 +416,42 @@ func (s *Storage) Keys() []string {
 //
 // then SubKeys("a.b") returns ["c", "d"].
 //
-// If the path points to a leaf value or structural conflict, an error is returned.
+// If the path points to a leaf value or structural conflict, a *ConflictError
+// is returned.
 // If the path does not exist, it returns nil.
-func (s *Storage) SubKeys(key string) (_ []string, err error) {
+func (s *Storage) SubKeys(key string) ([]string, error) {
+	if len(s.aliases) > 0 {
+		return s.aliasSubKeys(key)
+	}
+	return s.subKeysOf(key)
+}
+
+// subKeysOf is SubKeys without alias resolution.
+func (s *Storage) subKeysOf(key string) (_ []string, err error) {
+	if s.frozen != nil {
+		if n, ok := s.lookup(key); ok && !n.isLeaf() {
+			return slices.Clone(s.frozen.subKeys[n]), nil
+		}
+	}
 	var path []Path
 	if key != "" {
 		if path, err = SplitPath(key); err != nil {
 			return nil, err
 		}
 	}
+	return s.subKeys(key, path)
+}
 
+// subKeys returns the immediate child keys under the parsed path of key.
+func (s *Storage) subKeys(key string, path []Path) ([]string, error) {
 	if s.root == nil {
 		return nil, nil
 	}
 
-	// If the path is stored as an empty container, it has no children.
-	if _, ok := s.empty[key]; ok {
-		return []string{}, nil
-	}
-
-	// If the path is a leaf value, it's a conflict for requesting sub-keys.
-	if _, ok := s.data[key]; ok {
-		return nil, util.FormatError(nil, "property conflict at path %s", key)
-	}
-
 	n := s.root
 	for i, pathNode := range path {
-		if n == nil || pathNode.Type != n.Type {
-			return nil, util.FormatError(nil, "property conflict at path %s", JoinPath(path[:i+1]))
+		if n.isLeaf() || pathNode.Type != n.Type {
+			return nil, s.pathConflict(n, path, i)
 		}
 		v, ok := n.Data[pathNode.Elem]
 		if !ok {
@@ -203,77 +460,175 @@ func (s *Storage) SubKeys(key string) (_ []string, err error) {
 		n = v
 	}
 
-	if n == nil {
-		return []string{}, nil
+	if n.isLeaf() {
+		// If the path is stored as an empty container, it has no children.
+		if n.Value.IsEmpty() {
+			return []string{}, nil
+		}
+		// If the path is a leaf value, it's a conflict for requesting sub-keys.
+		return nil, s.conflictError(key, key, StructValue, StructContainer)
 	}
 	return util.OrderedMapKeys(n.Data), nil
 }
 
+// pathConflict creates the ConflictError for path[i], which cannot be
+// resolved against n, the node reached by path[:i].
+func (s *Storage) pathConflict(n *treeNode, path []Path, i int) *ConflictError {
+	existing := StructValue
+	if !n.isLeaf() {
+		existing = structOf(n.Type)
+	}
+	return s.conflictError(JoinPath(path[:i+1]), JoinPath(path[:i]), existing, structOf(path[i].Type))
+}
+
 // Has checks whether a given key (or path) exists in the Storage.
 // Returns true if the key refers to either a stored value, an empty
 // container, or a valid intermediate node in the hierarchy.
 func (s *Storage) Has(key string) bool {
-	if key == "" || s.root == nil {
+	if key == "" {
 		return false
 	}
+	_, ok := s.lookup(s.resolveAlias(key))
+	return ok
+}
 
-	// Check for empty containers.
-	if _, ok := s.empty[key]; ok {
-		return true
-	}
-
-	// Check for stored values.
-	if _, ok := s.data[key]; ok {
-		return true
-	}
-
-	path, err := SplitPath(key)
-	if err != nil {
-		return false
+// Lookup returns the ValueInfo stored under the given flattened key,
+// including nil values and empty containers, and whether it exists.
+func (s *Storage) Lookup(key string) (ValueInfo, bool) {
+	if key == "" {
+		return ValueInfo{}, false
 	}
+	n, ok := s.lookup(s.resolveAlias(key))
+	return leafValue(n, ok)
+}
 
-	n := s.root
-	for _, node := range path {
-		if n == nil || node.Type != n.Type {
-			return false
-		}
-		v, ok := n.Data[node.Elem]
-		if !ok {
-			return false
-		}
-		n = v
+// leafValue returns the value of a node found by a lookup.
+func leafValue(n *treeNode, ok bool) (ValueInfo, bool) {
+	if !ok || !n.isLeaf() {
+		return ValueInfo{}, false
 	}
-	return true
+	return n.Value, true
 }
 
 // Get retrieves the value associated with the given flattened key.
 // If the key is not found and a default value is provided, the default
 // is returned instead. Only the first default value is considered.
+// Nil values and empty containers are treated as not found.
 func (s *Storage) Get(key string, def ...string) string {
-	v, ok := s.data[key]
-	if !ok && len(def) > 0 {
-		return def[0]
+	n, ok := s.lookup(s.resolveAlias(key))
+	return nodeValueOrDefault(n, ok, def)
+}
+
+// nodeValueOrDefault is valueOrDefault for a node found by a lookup. It
+// doesn't copy the node's ValueInfo, which matters for Get.
+func nodeValueOrDefault(n *treeNode, ok bool, def []string) string {
+	if !ok || !n.isLeaf() || n.Value.IsEmpty() {
+		if len(def) > 0 {
+			return def[0]
+		}
+		return ""
+	}
+	return n.Value.Value
+}
+
+// valueOrDefault returns the string value, or the first default if the
+// value is absent, nil or an empty container.
+func valueOrDefault(v ValueInfo, ok bool, def []string) string {
+	if !ok || v.IsEmpty() {
+		if len(def) > 0 {
+			return def[0]
+		}
+		return ""
 	}
 	return v.Value
 }
 
 // Set inserts or updates a flattened key with the given value and
-// the index of the file it originated from.
+// the index of the file it originated from. The value must be a string,
+// which is always stored literally, a boolean or number (including
+// json.Number), whose type is preserved in ValueInfo, or one of the Nil,
+// EmptyMap and EmptySlice markers.
 //
 // It validates the path to prevent structural conflicts:
 //   - Cannot store a value where a container node already exists.
 //   - Cannot change an array branch into a map branch or vice versa.
 //
-// Returns an error if a structural conflict is detected.
-func (s *Storage) Set(key string, val string, file int8) error {
+// Returns a *ConflictError if a structural conflict is detected, or a
+// *TransformError if a transformer (see AddTransformer) rejects the value.
+func (s *Storage) Set(key string, val any, file int8) error {
+	if err := s.checkDefaults(key, file); err != nil {
+		return err
+	}
+	return s.setAny(key, val, file)
+}
+
+// checkDefaults returns an error if key is set by a file after the
+// defaults, see SetDefaults.
+func (s *Storage) checkDefaults(key string, file int8) error {
+	if idx, ok := s.file[DefaultsFile]; ok && file != idx && s.frozen == nil {
+		return util.FormatError(nil, "set key %s error: defaults are already set", key)
+	}
+	return nil
+}
+
+// setAny is Set without the check for defaults.
+func (s *Storage) setAny(key string, val any, file int8) error {
 	if key == "" {
-		return util.FormatError(nil, "key is empty")
+		return &PathSyntaxError{Key: key, Reason: "key is empty"}
+	}
+
+	info, err := newValueInfo(val, file)
+	if err != nil {
+		return util.FormatError(err, "invalid value for key %s", key)
 	}
 
 	path, err := SplitPath(key)
 	if err != nil {
 		return err
 	}
+	return s.set(key, path, info)
+}
+
+// SetAll stores every flattened key of m with the given file index, in
+// lexicographic key order. Unlike Set, it does not stop at the first
+// conflict: conflicting keys are skipped, the valid ones are stored, and
+// all conflicts are returned together as ConflictErrors. Only structural
+// conflicts are collected: as with Set, a value defined by another file
+// is overridden and not reported; see Loader.Overrides to list them.
+//
+// Errors other than conflicts, such as malformed keys or unsupported
+// values, abort the batch and are returned as is.
+func (s *Storage) SetAll(m map[string]any, file int8) error {
+	var conflicts ConflictErrors
+	for _, key := range util.OrderedMapKeys(m) {
+		err := s.Set(key, m[key], file)
+		if e, ok := err.(*ConflictError); ok {
+			e.AttemptedFile = s.fileName(file)
+			conflicts = append(conflicts, e)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(conflicts) > 0 {
+		return conflicts
+	}
+	return nil
+}
+
+// set stores a value under the parsed path of key.
+func (s *Storage) set(key string, path []Path, info ValueInfo) error {
+	if s.frozen != nil {
+		return errFrozen("set key " + key)
+	}
+	var changedBy []string
+	if len(s.transformers) > 0 {
+		var err error
+		if info, changedBy, err = s.transform(key, path, info); err != nil {
+			return err
+		}
+	}
 
 	// Initialize root if it's the first insertion
 	if s.root == nil {
@@ -281,35 +636,108 @@ func (s *Storage) Set(key string, val string, file int8) error {
 			Type: path[0].Type,
 			Data: make(map[string]*treeNode),
 		}
+		if s.index != nil {
+			s.index[""] = s.root
+		}
+		if s.work != nil {
+			s.work.owned[s.root] = true
+		}
 	}
 
 	n := s.root
+	if s.work != nil {
+		n = s.own(n)
+		s.root = n
+	}
+	end := 0      // end of the current segment in key
+	var id string // copy of key shared by the index entries of new containers
 	for i, pathNode := range path {
-		if n == nil || pathNode.Type != n.Type {
-			return util.FormatError(nil, "property conflict at path %s", JoinPath(path[:i+1]))
+		if n.isLeaf() || pathNode.Type != n.Type {
+			return s.pathConflict(n, path, i)
 		}
+		end = segmentEnd(end, i, pathNode)
 		v, ok := n.Data[pathNode.Elem]
 		if !ok {
+			v = &treeNode{}
 			if i < len(path)-1 {
-				v = &treeNode{
-					Type: path[i+1].Type,
-					Data: make(map[string]*treeNode),
+				v.Type = path[i+1].Type
+				v.Data = make(map[string]*treeNode)
+			}
+			n.Data[s.internString(pathNode.Elem)] = v
+			if s.index != nil && v.Data != nil {
+				if id == "" {
+					id = strings.Clone(key)
 				}
+				s.index[id[:end]] = v
+			}
+			if s.work != nil {
+				s.work.owned[v] = true
+			}
+		} else if s.work != nil {
+			if c := s.own(v); c != v {
+				n.Data[s.internString(pathNode.Elem)] = c
+				v = c
 			}
-			n.Data[pathNode.Elem] = v
 		}
 		n = v
 	}
-	if n != nil {
-		return util.FormatError(nil, "property conflict at path %s", key)
+	if !n.isLeaf() {
+		return s.conflictError(key, key, structOf(n.Type), StructValue)
 	}
 
 	// Store the value or empty container
-	switch val {
-	case "[]", "{}", "<nil>":
-		s.empty[key] = ValueInfo{file, val}
-	default:
-		s.data[key] = ValueInfo{file, val}
+	n.Value = info
+	if len(s.transformers) > 0 {
+		s.recordTransform(key, changedBy)
 	}
 	return nil
 }
+
+// segmentEnd returns the offset in a flattened key at which path
+// segment i ends, given the end of the previous segment.
+func segmentEnd(prev, i int, p Path) int {
+	if p.Type == PathTypeIndex {
+		return prev + len(p.Elem) + 2
+	}
+	if i > 0 {
+		prev++
+	}
+	return prev + len(p.Elem)
+}
+
+// reindex rebuilds the key index of a non-compact Storage, e.g. after
+// its tree was modified directly.
+func (s *Storage) reindex() {
+	s.index = make(map[string]*treeNode)
+	if s.root != nil {
+		s.indexNode(s.root, "")
+	}
+}
+
+// indexNode adds the container n, whose flattened key is key, and the
+// containers below it to the index.
+func (s *Storage) indexNode(n *treeNode, key string) {
+	s.index[key] = n
+	for elem, c := range n.Data {
+		if !c.isLeaf() {
+			s.indexNode(c, childKey(key, n.Type, elem))
+		}
+	}
+}
+
+// internString returns the canonical copy of a path segment. Segments
+// are cloned on first use so that they don't retain the whole key. A
+// working copy adds new segments to its own map, see commit.
+func (s *Storage) internString(elem string) string {
+	if v, ok := s.intern[elem]; ok {
+		return v
+	}
+	if s.work != nil {
+		if v, ok := s.work.intern[elem]; ok {
+			return v
+		}
+	}
+	v := strings.Clone(elem)
+	s.intern[v] = v
+	return v
+}
@@ -17,6 +17,9 @@
 package barky
 
 import (
+	"fmt"
+	"runtime"
+	"slices"
 	"testing"
 
 	"github.com/go-spring/spring-base/testing/assert"
@@ -66,7 +69,7 @@ func TestStorage(t *testing.T) {
 		assert.That(t, err).Nil()
 		assert.That(t, s.Has("a")).True()
 		assert.That(t, s.RawData()).Equal(map[string]ValueInfo{
-			"a": {0, "b"},
+			"a": {File: 0, Value: "b"},
 		})
 		assert.That(t, s.Data()).Equal(map[string]string{
 			"a": "b",
@@ -95,7 +98,7 @@ func TestStorage(t *testing.T) {
 		assert.That(t, err).Nil()
 		assert.That(t, s.Has("a")).True()
 		assert.That(t, s.RawData()).Equal(map[string]ValueInfo{
-			"a": {0, "c"},
+			"a": {File: 0, Value: "c"},
 		})
 
 		file := s.RawFile()
@@ -119,7 +122,7 @@ func TestStorage(t *testing.T) {
 		assert.That(t, s.Has("m")).True()
 		assert.That(t, s.Has("m.x")).True()
 		assert.That(t, s.RawData()).Equal(map[string]ValueInfo{
-			"m.x": {0, "y"},
+			"m.x": {File: 0, Value: "y"},
 		})
 		assert.That(t, s.Data()).Equal(map[string]string{
 			"m.x": "y",
@@ -154,7 +157,7 @@ func TestStorage(t *testing.T) {
 		assert.That(t, s.Has("m")).True()
 		assert.That(t, s.Has("m.x")).True()
 		assert.That(t, s.RawData()).Equal(map[string]ValueInfo{
-			"m.x": {0, "z"},
+			"m.x": {File: 0, Value: "z"},
 		})
 
 		err = s.Set("m.t", "q", fileID)
@@ -163,8 +166,8 @@ func TestStorage(t *testing.T) {
 		assert.That(t, s.Has("m.x")).True()
 		assert.That(t, s.Has("m.t")).True()
 		assert.That(t, s.RawData()).Equal(map[string]ValueInfo{
-			"m.x": {0, "z"},
-			"m.t": {0, "q"},
+			"m.x": {File: 0, Value: "z"},
+			"m.t": {File: 0, Value: "q"},
 		})
 
 		subKeys, err = s.SubKeys("m")
@@ -191,7 +194,7 @@ func TestStorage(t *testing.T) {
 		assert.That(t, err).Nil()
 		assert.That(t, s.Has("[0]")).True()
 		assert.That(t, s.RawData()).Equal(map[string]ValueInfo{
-			"[0]": {0, "p"},
+			"[0]": {File: 0, Value: "p"},
 		})
 		assert.That(t, s.Data()).Equal(map[string]string{
 			"[0]": "p",
@@ -205,7 +208,7 @@ func TestStorage(t *testing.T) {
 		err = s.Set("[0]", "w", fileID)
 		assert.That(t, err).Nil()
 		assert.That(t, s.RawData()).Equal(map[string]ValueInfo{
-			"[0]": {0, "w"},
+			"[0]": {File: 0, Value: "w"},
 		})
 
 		subKeys, err := s.SubKeys("")
@@ -216,8 +219,8 @@ func TestStorage(t *testing.T) {
 		assert.That(t, err).Nil()
 		assert.That(t, s.Has("[0]")).True()
 		assert.That(t, s.RawData()).Equal(map[string]ValueInfo{
-			"[0]": {0, "w"},
-			"[1]": {0, "p"},
+			"[0]": {File: 0, Value: "w"},
+			"[1]": {File: 0, Value: "p"},
 		})
 
 		subKeys, err = s.SubKeys("")
@@ -245,7 +248,7 @@ func TestStorage(t *testing.T) {
 		assert.That(t, s.Has("s")).True()
 		assert.That(t, s.Has("s[0]")).True()
 		assert.That(t, s.RawData()).Equal(map[string]ValueInfo{
-			"s[0]": {0, "p"},
+			"s[0]": {File: 0, Value: "p"},
 		})
 		assert.That(t, s.Data()).Equal(map[string]string{
 			"s[0]": "p",
@@ -257,8 +260,8 @@ func TestStorage(t *testing.T) {
 		assert.That(t, s.Has("s[0]")).True()
 		assert.That(t, s.Has("s[1]")).True()
 		assert.That(t, s.RawData()).Equal(map[string]ValueInfo{
-			"s[0]": {0, "p"},
-			"s[1]": {0, "o"},
+			"s[0]": {File: 0, Value: "p"},
+			"s[1]": {File: 0, Value: "o"},
 		})
 
 		subKeys, err := s.SubKeys("s")
@@ -295,7 +298,7 @@ func TestStorage(t *testing.T) {
 		assert.That(t, s.Has("a.b[0]")).True()
 		assert.That(t, s.Has("a.b[0].c")).True()
 		assert.That(t, s.RawData()).Equal(map[string]ValueInfo{
-			"a.b[0].c": {0, "123"},
+			"a.b[0].c": {File: 0, Value: "123"},
 		})
 		assert.That(t, s.Data()).Equal(map[string]string{
 			"a.b[0].c": "123",
@@ -309,8 +312,8 @@ func TestStorage(t *testing.T) {
 		assert.That(t, s.Has("a.b[0].d")).True()
 		assert.That(t, s.Has("a.b[0].d[0]")).True()
 		assert.That(t, s.RawData()).Equal(map[string]ValueInfo{
-			"a.b[0].c":    {0, "123"},
-			"a.b[0].d[0]": {0, "123"},
+			"a.b[0].c":    {File: 0, Value: "123"},
+			"a.b[0].d[0]": {File: 0, Value: "123"},
 		})
 
 		file := s.RawFile()
@@ -384,6 +387,21 @@ func TestStorage(t *testing.T) {
 		})
 	})
 
+	t.Run("too many files", func(t *testing.T) {
+		s := NewStorage()
+		for i := range 128 {
+			fileID, err := s.TryAddFile(fmt.Sprint(i))
+			assert.That(t, err).Nil()
+			assert.That(t, fileID).Equal(int8(i))
+		}
+		_, err := s.TryAddFile("128")
+		assert.Error(t, err).Matches("add file 128 error: too many files")
+		assert.That(t, s.AddFile("128")).Equal(int8(-1))
+		fileID, err := s.TryAddFile("127")
+		assert.That(t, err).Nil()
+		assert.That(t, fileID).Equal(int8(127))
+	})
+
 	t.Run("flatten & store", func(t *testing.T) {
 		m := FlattenMap(map[string]any{
 			"arr": []any{
@@ -468,32 +486,32 @@ func TestStorage(t *testing.T) {
 		s := NewStorage()
 		fileID := s.AddFile("test.go")
 
-		err := s.Set("empty_arr", "[]", fileID)
+		err := s.Set("empty_arr", EmptySlice, fileID)
 		assert.That(t, err).Nil()
 		assert.That(t, s.Has("empty_arr")).True()
 
-		_, inData := s.data["empty_arr"]
-		_, inEmpty := s.empty["empty_arr"]
-		assert.That(t, inData).False()
-		assert.That(t, inEmpty).True()
+		v, ok := s.Lookup("empty_arr")
+		assert.That(t, ok).True()
+		assert.That(t, v.IsEmpty()).True()
+		assert.That(t, s.Data()).Equal(map[string]string{})
 
-		err = s.Set("empty_obj", "{}", fileID)
+		err = s.Set("empty_obj", EmptyMap, fileID)
 		assert.That(t, err).Nil()
 		assert.That(t, s.Has("empty_obj")).True()
 
-		_, inData = s.data["empty_obj"]
-		_, inEmpty = s.empty["empty_obj"]
-		assert.That(t, inData).False()
-		assert.That(t, inEmpty).True()
+		v, ok = s.Lookup("empty_obj")
+		assert.That(t, ok).True()
+		assert.That(t, v.IsEmpty()).True()
+		assert.That(t, s.Data()).Equal(map[string]string{})
 
-		err = s.Set("nil_val", "<nil>", fileID)
+		err = s.Set("nil_val", Nil, fileID)
 		assert.That(t, err).Nil()
 		assert.That(t, s.Has("nil_val")).True()
 
-		_, inData = s.data["nil_val"]
-		_, inEmpty = s.empty["nil_val"]
-		assert.That(t, inData).False()
-		assert.That(t, inEmpty).True()
+		v, ok = s.Lookup("nil_val")
+		assert.That(t, ok).True()
+		assert.That(t, v.IsEmpty()).True()
+		assert.That(t, s.Data()).Equal(map[string]string{})
 
 		subKeys, err := s.SubKeys("empty_arr")
 		assert.That(t, err).Nil()
@@ -504,6 +522,42 @@ func TestStorage(t *testing.T) {
 		assert.That(t, subKeys).Equal([]string{})
 	})
 
+	t.Run("literal marker strings", func(t *testing.T) {
+		s := NewStorage()
+		fileID := s.AddFile("test.go")
+
+		for _, v := range []string{"[]", "{}", "<nil>"} {
+			err := s.Set("password", v, fileID)
+			assert.That(t, err).Nil()
+			assert.That(t, s.Get("password")).Equal(v)
+			assert.That(t, s.RawData()).Equal(map[string]ValueInfo{
+				"password": {File: 0, Value: v, Kind: KindString},
+			})
+		}
+
+		err := s.Set("password", Nil, fileID)
+		assert.That(t, err).Nil()
+		assert.That(t, s.Get("password")).Equal("")
+		assert.That(t, s.RawData()).Equal(map[string]ValueInfo{
+			"password": {File: 0, Value: "<nil>", Kind: KindNil},
+		})
+
+		err = s.Set("password", "[]", fileID)
+		assert.That(t, err).Nil()
+		assert.That(t, s.Get("password")).Equal("[]")
+		assert.That(t, s.RawData()).Equal(map[string]ValueInfo{
+			"password": {File: 0, Value: "[]", Kind: KindString},
+		})
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		s := NewStorage()
+		err := s.Set("a", 1+2i, 0)
+		assert.Error(t, err).Matches("invalid value for key a: unsupported value type complex128")
+		err = s.Set("a", Marker(0), 0)
+		assert.Error(t, err).Matches(`invalid value for key a: invalid marker Marker\(0\)`)
+	})
+
 	t.Run("RawData combines data and empty", func(t *testing.T) {
 		s := NewStorage()
 		fileID := s.AddFile("test.go")
@@ -511,13 +565,13 @@ func TestStorage(t *testing.T) {
 		err := s.Set("regular", "value", fileID)
 		assert.That(t, err).Nil()
 
-		err = s.Set("empty", "[]", fileID)
+		err = s.Set("empty", EmptySlice, fileID)
 		assert.That(t, err).Nil()
 
 		rawData := s.RawData()
 		assert.That(t, len(rawData)).Equal(2)
-		assert.That(t, rawData["regular"]).Equal(ValueInfo{0, "value"})
-		assert.That(t, rawData["empty"]).Equal(ValueInfo{0, "[]"})
+		assert.That(t, rawData["regular"]).Equal(ValueInfo{File: 0, Value: "value"})
+		assert.That(t, rawData["empty"]).Equal(ValueInfo{File: 0, Value: "[]", Kind: KindEmptySlice})
 
 		s2 := NewStorage()
 		err = s2.Set("key", "value", 0)
@@ -525,7 +579,7 @@ func TestStorage(t *testing.T) {
 
 		rawData2 := s2.RawData()
 		assert.That(t, len(rawData2)).Equal(1)
-		assert.That(t, rawData2["key"]).Equal(ValueInfo{0, "value"})
+		assert.That(t, rawData2["key"]).Equal(ValueInfo{File: 0, Value: "value"})
 	})
 
 	t.Run("path type conflicts", func(t *testing.T) {
@@ -571,3 +625,244 @@ func TestStorage(t *testing.T) {
 		assert.That(t, subKeys).Equal([]string{"j"})
 	})
 }
+
+// checkKeyIndex asserts that the key index of s matches its tree.
+func checkKeyIndex(t *testing.T, s *Storage) {
+	t.Helper()
+	got := s.index
+	s.reindex()
+	assert.That(t, got).Equal(s.index)
+}
+
+func TestStorageIndex(t *testing.T) {
+	const doc = `{"a":{"b":[1,2,3],"c":"x"},"d":[{"e":1}],"f":{}}`
+	keys := []string{"", "a", "a.b", "a.b[0]", "a.b[2]", "a.b[3]", "a.c", "d[0].e", "f", "g", "g.h", "g.h[0]", "x"}
+
+	ops := []func(s *Storage) error{
+		func(s *Storage) error {
+			return s.ApplyPatch("p1", []byte(`[
+				{"op":"remove","path":"/a/b/0"},
+				{"op":"add","path":"/a/b/0","value":{"z":true}},
+				{"op":"move","from":"/d","path":"/g"},
+				{"op":"add","path":"/f/x","value":1}
+			]`))
+		},
+		func(s *Storage) error {
+			return s.ApplyMergePatch("p2", []byte(`{"a":{"c":null},"x":[1]}`))
+		},
+		func(s *Storage) error {
+			return s.Move("g", "h")
+		},
+		func(s *Storage) error {
+			return s.Set("g.h[0]", "v", 0)
+		},
+	}
+
+	s, c := newJSONStorage(t, doc), NewCompactStorage()
+	v, err := decodePatchValue([]byte(doc))
+	assert.That(t, err).Nil()
+	file := c.AddFile("base.json")
+	assert.That(t, c.setValue("", v, file)).Nil()
+	checkKeyIndex(t, s)
+	assert.That(t, c.index).Nil()
+
+	for _, op := range ops {
+		assert.That(t, op(s)).Nil()
+		assert.That(t, op(c)).Nil()
+		checkKeyIndex(t, s)
+		assert.That(t, c.index).Nil()
+		for _, key := range keys {
+			assert.That(t, s.Has(key)).Equal(c.Has(key))
+			v1, ok1 := s.Lookup(key)
+			v2, ok2 := c.Lookup(key)
+			assert.That(t, ok1).Equal(ok2)
+			assert.That(t, v1).Equal(v2)
+		}
+		assert.That(t, jsonOf(t, s)).Equal(jsonOf(t, c))
+	}
+	assert.That(t, s.Get("h[0].e")).Equal("1")
+	assert.That(t, s.Get("g.h[0]")).Equal("v")
+	assert.That(t, s.Has("d")).False()
+}
+
+func TestStorageIterators(t *testing.T) {
+	s := NewStorage()
+	fileID := s.AddFile("store_test.go")
+	for k, v := range map[string]any{
+		"a.b[0].c":  "1",
+		"a.b[1]":    "2",
+		"a.b[10]":   "3",
+		"a.b[2]":    "4",
+		"a.d":       "5",
+		"a.empty":   EmptyMap,
+		"z":         "6",
+		"arr[0][1]": "7",
+	} {
+		err := s.Set(k, v, fileID)
+		assert.That(t, err).Nil()
+	}
+
+	t.Run("all", func(t *testing.T) {
+		m := make(map[string]ValueInfo)
+		for k, v := range s.All() {
+			m[k] = v
+		}
+		assert.That(t, m).Equal(s.RawData())
+
+		count := 0
+		for range s.All() {
+			count++
+			break
+		}
+		assert.That(t, count).Equal(1)
+	})
+
+	t.Run("walk", func(t *testing.T) {
+		var keys []string
+		for k := range s.Walk("") {
+			keys = append(keys, k)
+		}
+		assert.That(t, keys).Equal([]string{
+			"a.b[0].c", "a.b[1]", "a.b[2]", "a.b[10]", "a.d", "a.empty", "arr[0][1]", "z",
+		})
+
+		keys = nil
+		for k, v := range s.Walk("a.b") {
+			keys = append(keys, k+"="+v.Value)
+		}
+		assert.That(t, keys).Equal([]string{
+			"a.b[0].c=1", "a.b[1]=2", "a.b[2]=4", "a.b[10]=3",
+		})
+
+		keys = nil
+		for k := range s.Walk("a.d") {
+			keys = append(keys, k)
+		}
+		assert.That(t, keys).Equal([]string{"a.d"})
+
+		keys = nil
+		for k := range s.Walk("a") {
+			keys = append(keys, k)
+			if len(keys) == 2 {
+				break
+			}
+		}
+		assert.That(t, keys).Equal([]string{"a.b[0].c", "a.b[1]"})
+
+		for _, prefix := range []string{"x", "a.b.c", "a[", "a.d.e"} {
+			for range s.Walk(prefix) {
+				t.Fatalf("unexpected value for prefix %q", prefix)
+			}
+		}
+
+		for range NewStorage().Walk("") {
+			t.Fatal("unexpected value in empty storage")
+		}
+	})
+
+	t.Run("children", func(t *testing.T) {
+		var keys []string
+		for k := range s.Children("a") {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		assert.That(t, keys).Equal([]string{"b", "d", "empty"})
+
+		keys = nil
+		for k := range s.Children("") {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		assert.That(t, keys).Equal([]string{"a", "arr", "z"})
+
+		for _, key := range []string{"a.d", "a.empty", "x", "a["} {
+			for range s.Children(key) {
+				t.Fatalf("unexpected child for key %q", key)
+			}
+		}
+	})
+}
+
+// benchKeys generates n deep keys that share long prefixes, similar to
+// those found in large generated configuration files.
+func benchKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("app.services.svc%d.endpoints[%d].config.option%d", i/200, i/20%10, i%20)
+	}
+	return keys
+}
+
+func newBenchStorage(keys []string) *Storage {
+	return fillBenchStorage(NewStorage(), keys)
+}
+
+func fillBenchStorage(s *Storage, keys []string) *Storage {
+	fileID := s.AddFile("bench.yaml")
+	for _, k := range keys {
+		if err := s.Set(k, "value", fileID); err != nil {
+			panic(err)
+		}
+	}
+	return s
+}
+
+func BenchmarkStorage(b *testing.B) {
+	const n = 100_000
+
+	for _, layout := range []struct {
+		name string
+		new  func() *Storage
+	}{{"", NewStorage}, {"-compact", NewCompactStorage}} {
+
+		b.Run("set"+layout.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				fillBenchStorage(layout.new(), benchKeys(n))
+			}
+		})
+
+		b.Run("memory"+layout.name, func(b *testing.B) {
+			var s *Storage
+			var before, after runtime.MemStats
+			for b.Loop() {
+				keys := benchKeys(n)
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+				s = fillBenchStorage(layout.new(), keys)
+				keys = nil
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+			}
+			b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc)/n, "heap-bytes/leaf")
+			runtime.KeepAlive(s)
+		})
+
+		b.Run("get"+layout.name, func(b *testing.B) {
+			keys := benchKeys(n)
+			s := fillBenchStorage(layout.new(), keys)
+			b.ReportAllocs()
+			i := 0
+			for b.Loop() {
+				s.Get(keys[i%n])
+				i++
+			}
+		})
+
+		b.Run("has"+layout.name, func(b *testing.B) {
+			s := fillBenchStorage(layout.new(), benchKeys(n))
+			b.ReportAllocs()
+			for b.Loop() {
+				s.Has("app.services.svc1.endpoints[2]")
+			}
+		})
+
+		b.Run("keys"+layout.name, func(b *testing.B) {
+			s := fillBenchStorage(layout.new(), benchKeys(n))
+			b.ReportAllocs()
+			for b.Loop() {
+				s.Keys()
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/go-spring/spring-base/util"
+)
+
+// TokenReader is a source of JSON-style tokens, as produced by
+// (*json.Decoder).Token. Containers are delimited by json.Delim values
+// ('{', '}', '[', ']'), object keys are strings, and scalars are
+// string, bool, float64, json.Number or nil.
+//
+// *json.Decoder is a TokenReader for JSON, and YAMLTokenReader adapts
+// YAML events to it.
+type TokenReader interface {
+	Token() (json.Token, error)
+}
+
+// streamFrame is an open container on the streaming flattener's stack.
+type streamFrame struct {
+	key   string // flattened key of the container itself
+	array bool   // whether the container is an array
+	count int    // number of children seen so far
+}
+
+// FlattenReader decodes a single JSON document from r and flattens it
+// in a streaming fashion, calling fn for every (key, value) pair, where
+// value is a string or a Marker (or a typed scalar if f.KeepTypes is
+// set). Numbers are emitted in their original textual form.
+// See FlattenTokens for details.
+func FlattenReader(r io.Reader, fn func(key string, value any) error) error {
+	return defaultFlattener.FlattenReader(r, fn)
+}
+
+// FlattenTokens flattens a single document read from a token stream,
+// calling fn for every (key, value) pair in document order. It follows
+// the same conventions as FlattenMap, but never materializes the whole
+// document: memory use is bounded by the nesting depth.
+//
+// The document must be an object or an array; a top-level array yields
+// keys such as "[0].name". If fn returns an error, flattening stops and
+// that error is returned. Tokens following the document are not read,
+// so the same reader can be used to flatten consecutive documents.
+func FlattenTokens(tr TokenReader, fn func(key string, value any) error) error {
+	return defaultFlattener.FlattenTokens(tr, fn)
+}
+
+// FlattenReader is like the package-level FlattenReader, but follows
+// the conventions of f.
+func (f *Flattener) FlattenReader(r io.Reader, fn func(key string, value any) error) error {
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	return f.FlattenTokens(d, fn)
+}
+
+// FlattenTokens is like the package-level FlattenTokens, but follows
+// the conventions of f.
+func (f *Flattener) FlattenTokens(tr TokenReader, fn func(key string, value any) error) error {
+	tok, err := tr.Token()
+	if err != nil {
+		return util.FormatError(err, "read token error")
+	}
+	switch tok {
+	case json.Delim('{'), json.Delim('['):
+	default:
+		return util.FormatError(nil, "unexpected token %v: document must be an object or an array", tok)
+	}
+
+	stack := []streamFrame{{array: tok == json.Delim('[')}}
+	for len(stack) > 0 {
+		if tok, err = tr.Token(); err != nil {
+			return util.FormatError(err, "read token error")
+		}
+		top := &stack[len(stack)-1]
+
+		// Close the current container, reporting it if it was empty.
+		if tok == json.Delim('}') || tok == json.Delim(']') {
+			if top.count == 0 && len(stack) > 1 {
+				v := f.EmptyMapValue
+				if top.array {
+					v = f.EmptySliceValue
+				}
+				if err = fn(top.key, v); err != nil {
+					return err
+				}
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		var key string
+		if top.array {
+			key = f.joinIndex(top.key, strconv.Itoa(top.count))
+		} else {
+			s, ok := tok.(string)
+			if !ok {
+				return util.FormatError(nil, "unexpected token %v: expect an object key", tok)
+			}
+			key = f.joinKey(top.key, s)
+			if tok, err = tr.Token(); err != nil {
+				return util.FormatError(err, "read token error")
+			}
+		}
+		top.count++
+
+		switch v := tok.(type) {
+		case json.Delim:
+			switch v {
+			case '{', '[':
+				stack = append(stack, streamFrame{key: key, array: v == '['})
+			default:
+				return util.FormatError(nil, "unexpected token %v at key %s", tok, key)
+			}
+		case nil:
+			err = fn(key, f.NilValue)
+		default:
+			err = fn(key, f.scalar(v))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
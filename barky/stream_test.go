@@ -0,0 +1,132 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/spring-base/testing/assert"
+)
+
+func TestFlattenReader(t *testing.T) {
+
+	collect := func(s string) (map[string]any, error) {
+		m := make(map[string]any)
+		err := FlattenReader(strings.NewReader(s), func(key string, value any) error {
+			m[key] = value
+			return nil
+		})
+		return m, err
+	}
+
+	t.Run("same as FlattenMap", func(t *testing.T) {
+		const doc = `{
+			"a": "123", "b": 4.5, "c": true, "d": null,
+			"arr": ["abc", {"x": 1, "y": [1, 2]}, null, [], {}],
+			"map": {"nil": null, "empty_arr": [], "empty_map": {}}
+		}`
+		m, err := collect(doc)
+		assert.That(t, err).Nil()
+
+		var v map[string]any
+		err = json.Unmarshal([]byte(doc), &v)
+		assert.That(t, err).Nil()
+		assert.That(t, m).Equal(FlattenMap(v))
+	})
+
+	t.Run("keeps number text", func(t *testing.T) {
+		m, err := collect(`{"big": 12345678901234567890, "f": 1.50}`)
+		assert.That(t, err).Nil()
+		assert.That(t, m).Equal(map[string]any{
+			"big": "12345678901234567890",
+			"f":   "1.50",
+		})
+	})
+
+	t.Run("top-level array", func(t *testing.T) {
+		m, err := collect(`[{"name": "a"}, "b"]`)
+		assert.That(t, err).Nil()
+		assert.That(t, m).Equal(map[string]any{
+			"[0].name": "a",
+			"[1]":      "b",
+		})
+	})
+
+	t.Run("empty document", func(t *testing.T) {
+		m, err := collect(`{}`)
+		assert.That(t, err).Nil()
+		assert.That(t, m).Equal(map[string]any{})
+	})
+
+	t.Run("scalar document", func(t *testing.T) {
+		_, err := collect(`"abc"`)
+		assert.Error(t, err).Matches("document must be an object or an array")
+	})
+
+	t.Run("malformed document", func(t *testing.T) {
+		_, err := collect(`{"a": [1, 2}`)
+		assert.Error(t, err).Matches("read token error")
+	})
+
+	t.Run("stop on error", func(t *testing.T) {
+		var keys []string
+		err := FlattenReader(strings.NewReader(`{"a": 1, "b": 2, "c": 3}`), func(key string, value any) error {
+			keys = append(keys, key)
+			if key == "b" {
+				return errors.New("stop")
+			}
+			return nil
+		})
+		assert.Error(t, err).Matches("stop")
+		assert.That(t, keys).Equal([]string{"a", "b"})
+	})
+
+	t.Run("consecutive documents", func(t *testing.T) {
+		d := json.NewDecoder(strings.NewReader(`{"a": 1} {"b": 2}`))
+		var keys []string
+		fn := func(key string, value any) error {
+			keys = append(keys, key+"="+value.(string))
+			return nil
+		}
+		assert.That(t, FlattenTokens(d, fn)).Nil()
+		assert.That(t, FlattenTokens(d, fn)).Nil()
+		assert.That(t, keys).Equal([]string{"a=1", "b=2"})
+	})
+}
+
+func TestFlattenerReader(t *testing.T) {
+	f := NewFlattener()
+	f.Separator = "__"
+	f.IndexStyle = IndexSegment
+	f.NilValue = ""
+	f.EmptySliceValue = ""
+
+	m := make(map[string]any)
+	err := f.FlattenReader(strings.NewReader(`{"a": {"b": [1, null, []]}}`), func(key string, value any) error {
+		m[key] = value
+		return nil
+	})
+	assert.That(t, err).Nil()
+	assert.That(t, m).Equal(map[string]any{
+		"a__b__0": "1",
+		"a__b__1": "",
+		"a__b__2": "",
+	})
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-spring/spring-base/util"
+)
+
+// Transformer normalizes a string value stored under key. It returns
+// the new value, or an error to reject the value.
+type Transformer func(key, value string) (string, error)
+
+// transformer is a Transformer registered on a Storage.
+type transformer struct {
+	name    string
+	pattern *Pattern
+	fn      Transformer
+}
+
+// AddTransformer registers a named Transformer for the keys matching
+// pattern (see CompilePattern). Transformers run in registration order
+// whenever a string value is set, each one receiving the output of the
+// previous one; booleans, numbers, nil values and empty containers are
+// left alone. Values stored before the registration are only affected
+// by Transform.
+func (s *Storage) AddTransformer(name, pattern string, fn Transformer) error {
+	if s.frozen != nil {
+		return errFrozen("add transformer " + name)
+	}
+	p, err := CompilePattern(pattern)
+	if err != nil {
+		return err
+	}
+	s.transformers = append(s.transformers, transformer{name: name, pattern: p, fn: fn})
+	return nil
+}
+
+// transform runs the matching transformers on a value to be stored
+// under key. It returns the new value and the names of the transformers
+// that changed it.
+func (s *Storage) transform(key string, path []Path, info ValueInfo) (ValueInfo, []string, error) {
+	if info.Kind != KindString {
+		return info, nil, nil
+	}
+	var changedBy []string
+	for _, t := range s.transformers {
+		if !t.pattern.MatchPath(path) {
+			continue
+		}
+		v, err := t.fn(key, info.Value)
+		if err != nil {
+			return info, nil, &TransformError{Path: key, Transformer: t.name, Err: err}
+		}
+		if v != info.Value {
+			info.Value = v
+			changedBy = append(changedBy, t.name)
+		}
+	}
+	return info, changedBy, nil
+}
+
+// recordTransform records the transformers that changed the value of key.
+func (s *Storage) recordTransform(key string, changedBy []string) {
+	if len(changedBy) == 0 {
+		delete(s.transformed, key)
+		return
+	}
+	if s.transformed == nil {
+		s.transformed = make(map[string][]string)
+	}
+	s.transformed[key] = changedBy
+}
+
+// Transform runs the registered transformers over every stored value,
+// for instance after transformers were added to a loaded Storage.
+// Rejected values are kept unchanged, and all rejections are returned
+// together, joined by errors.Join.
+func (s *Storage) Transform() error {
+	if s.frozen != nil {
+		return errFrozen("transform")
+	}
+	var errs []error
+	for key := range s.Walk("") {
+		path, err := SplitPath(key)
+		if err != nil {
+			return err
+		}
+		n, _ := s.lookup(key)
+		info, changedBy, err := s.transform(key, path, n.Value)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if len(changedBy) > 0 {
+			n.Value = info
+			s.recordTransform(key, append(slices.Clone(s.transformed[key]), changedBy...))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// TransformedBy returns the names of the transformers that changed the
+// current value of key, in the order they ran, or nil if none did.
+func (s *Storage) TransformedBy(key string) []string {
+	return slices.Clone(s.transformed[key])
+}
+
+// TrimSpace is a Transformer that removes leading and trailing white space.
+func TrimSpace(key, value string) (string, error) {
+	return strings.TrimSpace(value), nil
+}
+
+// ToLower is a Transformer that converts a value to lower case, e.g. to
+// normalize enumerations.
+func ToLower(key, value string) (string, error) {
+	return strings.ToLower(value), nil
+}
+
+// ExpandPath is a Transformer for file paths. It expands environment
+// variables such as "$HOME" or "${HOME}", and a leading "~" to the
+// current user's home directory.
+func ExpandPath(key, value string) (string, error) {
+	value = os.ExpandEnv(value)
+	if value == "~" || strings.HasPrefix(value, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		value = filepath.Join(home, value[1:])
+	}
+	return value, nil
+}
+
+// CanonicalDuration is a Transformer that rewrites a duration such as
+// "90s" in its canonical form "1m30s", and rejects invalid durations.
+func CanonicalDuration(key, value string) (string, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return "", err
+	}
+	return d.String(), nil
+}
+
+// OneOf returns a Transformer that rejects values other than the given
+// ones, e.g. to validate enumerations.
+func OneOf(values ...string) Transformer {
+	return func(key, value string) (string, error) {
+		if !slices.Contains(values, value) {
+			return "", util.FormatError(nil, "value %q is not one of %s", value, strings.Join(values, ", "))
+		}
+		return value, nil
+	}
+}
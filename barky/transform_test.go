@@ -0,0 +1,119 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-spring/spring-base/testing/assert"
+)
+
+func TestTransformer(t *testing.T) {
+
+	t.Run("set", func(t *testing.T) {
+		s := NewStorage()
+		assert.That(t, s.AddTransformer("trim", "**", TrimSpace)).Nil()
+		assert.That(t, s.AddTransformer("duration", "**.timeout", CanonicalDuration)).Nil()
+		assert.That(t, s.AddTransformer("lower", "log.level", ToLower)).Nil()
+		assert.That(t, s.AddTransformer("level", "log.level", OneOf("debug", "info", "warn"))).Nil()
+		assert.Error(t, s.AddTransformer("bad", "a[", TrimSpace)).Matches("unclosed")
+
+		assert.That(t, s.Set("name", " app ", 0)).Nil()
+		assert.That(t, s.Set("db.timeout", " 90s", 0)).Nil()
+		assert.That(t, s.Set("log.level", "INFO", 0)).Nil()
+		assert.That(t, s.Set("port", 8080, 0)).Nil()
+		assert.That(t, s.Set("host", "localhost", 0)).Nil()
+		assert.That(t, s.Set("empty", EmptyMap, 0)).Nil()
+
+		assert.That(t, s.Data()).Equal(map[string]string{
+			"name":       "app",
+			"db.timeout": "1m30s",
+			"log.level":  "info",
+			"port":       "8080",
+			"host":       "localhost",
+		})
+		assert.That(t, s.TransformedBy("name")).Equal([]string{"trim"})
+		assert.That(t, s.TransformedBy("db.timeout")).Equal([]string{"trim", "duration"})
+		assert.That(t, s.TransformedBy("log.level")).Equal([]string{"lower"})
+		assert.That(t, s.TransformedBy("host")).Nil()
+		assert.That(t, s.TransformedBy("port")).Nil()
+
+		// A new value replaces the record.
+		assert.That(t, s.Set("name", "app2", 0)).Nil()
+		assert.That(t, s.TransformedBy("name")).Nil()
+
+		err := s.Set("log.level", "trace", 0)
+		var e *TransformError
+		assert.That(t, errors.As(err, &e)).True()
+		assert.That(t, e.Path).Equal("log.level")
+		assert.That(t, e.Transformer).Equal("level")
+		assert.Error(t, err).Matches(`^invalid value at path log.level \(transformer level\): value "trace" is not one of debug, info, warn$`)
+		assert.That(t, s.Get("log.level")).Equal("info")
+
+		err = s.SetCompiled(MustCompile("http.timeout"), "10", 0)
+		assert.Error(t, err).Matches(`invalid value at path http.timeout \(transformer duration\): time: missing unit in duration "10"`)
+		assert.That(t, s.Has("http")).False()
+	})
+
+	t.Run("on demand", func(t *testing.T) {
+		s := NewStorage()
+		assert.That(t, s.Set("name", " app ", 0)).Nil()
+		assert.That(t, s.Set("a.timeout", "60s", 0)).Nil()
+		assert.That(t, s.Set("b.timeout", "x", 0)).Nil()
+		assert.That(t, s.Set("c.timeout", "y", 0)).Nil()
+
+		assert.That(t, s.AddTransformer("duration", "*.timeout", CanonicalDuration)).Nil()
+		assert.That(t, s.AddTransformer("trim", "name", TrimSpace)).Nil()
+		assert.That(t, s.Get("name")).Equal(" app ")
+
+		err := s.Transform()
+		assert.Error(t, err).Matches(`invalid value at path b.timeout \(transformer duration\)`)
+		assert.Error(t, err).Matches(`invalid value at path c.timeout \(transformer duration\)`)
+		assert.That(t, s.Data()).Equal(map[string]string{
+			"name":      "app",
+			"a.timeout": "1m0s",
+			"b.timeout": "x",
+			"c.timeout": "y",
+		})
+		assert.That(t, s.TransformedBy("a.timeout")).Equal([]string{"duration"})
+
+		assert.That(t, s.Set("b.timeout", "1s", 0)).Nil()
+		assert.That(t, s.Set("c.timeout", "2s", 0)).Nil()
+		assert.That(t, s.Transform()).Nil()
+	})
+
+	t.Run("expand path", func(t *testing.T) {
+		home, err := os.UserHomeDir()
+		assert.That(t, err).Nil()
+		t.Setenv("BARKY_DIR", "/var/barky")
+
+		for value, expect := range map[string]string{
+			"~":                   home,
+			"~/logs":              filepath.Join(home, "logs"),
+			"$BARKY_DIR/data":     "/var/barky/data",
+			"${BARKY_DIR}/x":      "/var/barky/x",
+			"/etc/~user/file.txt": "/etc/~user/file.txt",
+		} {
+			v, err := ExpandPath("path", value)
+			assert.That(t, err).Nil()
+			assert.That(t, v).Equal(expect)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-spring/spring-base/util"
+)
+
+// ANSI escape sequences used by Tree.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiBlue   = "\x1b[34m"
+	ansiCyan   = "\x1b[36m"
+	ansiGray   = "\x1b[90m"
+)
+
+// TreeOptions controls how Storage.Tree prints the tree.
+type TreeOptions struct {
+	// Prefix selects the subtree to print. Defaults to the whole Storage.
+	Prefix string
+
+	// MaxDepth limits the number of levels printed below the prefix;
+	// deeper containers are elided as "{...}" or "[...]". Zero means
+	// no limit.
+	MaxDepth int
+
+	// Color highlights keys, values and files with ANSI escape sequences.
+	Color bool
+}
+
+// Tree prints the tree under opts.Prefix for debugging, one node per
+// line, e.g.:
+//
+//	db {}
+//	├── hosts []
+//	│   ├── [0] = "a"  # a.yaml
+//	│   └── [1] = "b"  # b.yaml
+//	├── opts = {}  # a.yaml
+//	└── port = 5432  # a.yaml
+//
+// Maps are marked by "{}" and arrays by "[]" after their name, and
+// values follow "=": strings are quoted, while booleans, numbers, nil
+// values and empty containers are printed as is. Each value is followed
+// by the name of its file, if known. The root of the whole Storage is
+// printed as ".". It returns an error if the prefix is invalid or absent.
+func (s *Storage) Tree(w io.Writer, opts TreeOptions) error {
+	n, ok := s.lookup(opts.Prefix)
+	if !ok {
+		if opts.Prefix != "" {
+			if _, err := SplitPath(opts.Prefix); err != nil {
+				return err
+			}
+			return util.FormatError(nil, "key %s does not exist", opts.Prefix)
+		}
+		n = &treeNode{Data: map[string]*treeNode{}}
+	}
+	label := opts.Prefix
+	if label == "" {
+		label = "."
+	}
+	p := &treePrinter{s: s, opts: opts}
+	p.node(n, label, "", "", 0)
+	_, err := io.WriteString(w, p.sb.String())
+	return err
+}
+
+// treePrinter renders a subtree for Storage.Tree.
+type treePrinter struct {
+	s    *Storage
+	opts TreeOptions
+	sb   strings.Builder
+}
+
+// node prints n, labelled label, after the indentation first, and its
+// children after the indentation rest.
+func (p *treePrinter) node(n *treeNode, label, first, rest string, depth int) {
+	p.sb.WriteString(first)
+	p.sb.WriteString(p.color(ansiBlue, label))
+
+	if n.isLeaf() {
+		p.sb.WriteString(" = ")
+		switch v := n.Value; v.Kind {
+		case KindString:
+			p.sb.WriteString(p.color(ansiGreen, strconv.Quote(v.Value)))
+		case KindNil, KindEmptyMap, KindEmptySlice:
+			p.sb.WriteString(p.color(ansiCyan, v.Value))
+		default:
+			p.sb.WriteString(p.color(ansiYellow, v.Value))
+		}
+		if file := p.s.fileName(n.Value.File); file != "" {
+			p.sb.WriteString(p.color(ansiGray, "  # "+file))
+		}
+		p.sb.WriteByte('\n')
+		return
+	}
+
+	open, closing := "{", "}"
+	if n.Type == PathTypeIndex {
+		open, closing = "[", "]"
+	}
+	if p.opts.MaxDepth > 0 && depth >= p.opts.MaxDepth && len(n.Data) > 0 {
+		p.sb.WriteString(p.color(ansiCyan, " "+open+"..."+closing) + "\n")
+		return
+	}
+	p.sb.WriteString(p.color(ansiCyan, " "+open+closing) + "\n")
+
+	elems := slices.Collect(maps.Keys(n.Data))
+	if n.Type == PathTypeIndex {
+		slices.SortFunc(elems, compareIndex)
+	} else {
+		slices.Sort(elems)
+	}
+	for i, elem := range elems {
+		branch, indent := "├── ", "│   "
+		if i == len(elems)-1 {
+			branch, indent = "└── ", "    "
+		}
+		childLabel := elem
+		if n.Type == PathTypeIndex {
+			childLabel = "[" + elem + "]"
+		}
+		p.node(n.Data[elem], childLabel, rest+branch, rest+indent, depth+1)
+	}
+}
+
+// color wraps s in the given ANSI color if colors are enabled.
+func (p *treePrinter) color(code, s string) string {
+	if !p.opts.Color {
+		return s
+	}
+	return code + s + ansiReset
+}
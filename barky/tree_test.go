@@ -0,0 +1,97 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/go-spring/spring-base/testing/assert"
+)
+
+func TestTree(t *testing.T) {
+	s := NewStorage()
+	a := s.AddFile("a.yaml")
+	b := s.AddFile("b.yaml")
+	for key, val := range map[string]any{
+		"db.hosts[0]":  "a",
+		"db.hosts[10]": "c",
+		"db.hosts[2]":  "b",
+		"db.opts":      EmptyMap,
+		"db.port":      5432,
+		"db.tls":       Nil,
+		"name":         "app \"x\"",
+		"tags":         EmptySlice,
+	} {
+		assert.That(t, s.Set(key, val, a)).Nil()
+	}
+	assert.That(t, s.Set("db.hosts[2]", "b", b)).Nil()
+	assert.That(t, s.Set("debug", true, 5)).Nil()
+
+	tree := func(opts TreeOptions) string {
+		var buf bytes.Buffer
+		assert.That(t, s.Tree(&buf, opts)).Nil()
+		return buf.String()
+	}
+
+	assert.That(t, tree(TreeOptions{})).Equal(`. {}
+├── db {}
+│   ├── hosts []
+│   │   ├── [0] = "a"  # a.yaml
+│   │   ├── [2] = "b"  # b.yaml
+│   │   └── [10] = "c"  # a.yaml
+│   ├── opts = {}  # a.yaml
+│   ├── port = 5432  # a.yaml
+│   └── tls = <nil>  # a.yaml
+├── debug = true
+├── name = "app \"x\""  # a.yaml
+└── tags = []  # a.yaml
+`)
+
+	assert.That(t, tree(TreeOptions{Prefix: "db", MaxDepth: 1})).Equal(`db {}
+├── hosts [...]
+├── opts = {}  # a.yaml
+├── port = 5432  # a.yaml
+└── tls = <nil>  # a.yaml
+`)
+
+	assert.That(t, tree(TreeOptions{MaxDepth: 1})).Equal(`. {}
+├── db {...}
+├── debug = true
+├── name = "app \"x\""  # a.yaml
+└── tags = []  # a.yaml
+`)
+
+	assert.That(t, tree(TreeOptions{Prefix: "db.port", Color: true})).Equal(
+		"\x1b[34mdb.port\x1b[0m = \x1b[33m5432\x1b[0m\x1b[90m  # a.yaml\x1b[0m\n")
+
+	assert.That(t, tree(TreeOptions{Prefix: "db.hosts", Color: true})).Equal(
+		"\x1b[34mdb.hosts\x1b[0m\x1b[36m []\x1b[0m\n" +
+			"├── \x1b[34m[0]\x1b[0m = \x1b[32m\"a\"\x1b[0m\x1b[90m  # a.yaml\x1b[0m\n" +
+			"├── \x1b[34m[2]\x1b[0m = \x1b[32m\"b\"\x1b[0m\x1b[90m  # b.yaml\x1b[0m\n" +
+			"└── \x1b[34m[10]\x1b[0m = \x1b[32m\"c\"\x1b[0m\x1b[90m  # a.yaml\x1b[0m\n")
+
+	var buf bytes.Buffer
+	assert.That(t, NewStorage().Tree(&buf, TreeOptions{})).Nil()
+	assert.That(t, buf.String()).Equal(". {}\n")
+
+	err := s.Tree(&buf, TreeOptions{Prefix: "db.user"})
+	assert.Error(t, err).Matches("key db.user does not exist")
+	err = s.Tree(&buf, TreeOptions{Prefix: "db["})
+	assert.That(t, errors.Is(err, ErrInvalidPath)).True()
+}
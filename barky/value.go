@@ -0,0 +1,147 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/go-spring/spring-base/util"
+)
+
+// String returns the name of the kind.
+func (k Kind) String() string {
+	switch k {
+	case KindString:
+		return "string"
+	case KindBool:
+		return "bool"
+	case KindInt:
+		return "int"
+	case KindUint:
+		return "uint"
+	case KindFloat:
+		return "float"
+	case KindNil:
+		return "nil"
+	case KindEmptyMap:
+		return "empty map"
+	case KindEmptySlice:
+		return "empty slice"
+	default:
+		return "Kind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
+
+// IsEmpty reports whether the value is a nil value or an empty container.
+func (v ValueInfo) IsEmpty() bool {
+	switch v.Kind {
+	case KindNil, KindEmptyMap, KindEmptySlice:
+		return true
+	default:
+		return false
+	}
+}
+
+// Typed returns the typed form of the value: the original value for
+// booleans and numbers, nil for nil values, an empty map or slice for
+// empty containers, and the string itself otherwise.
+func (v ValueInfo) Typed() any {
+	switch v.Kind {
+	case KindBool, KindInt, KindUint, KindFloat:
+		return v.Raw
+	case KindNil:
+		return nil
+	case KindEmptyMap:
+		return map[string]any{}
+	case KindEmptySlice:
+		return []any{}
+	default:
+		return v.Value
+	}
+}
+
+// newValueInfo creates a ValueInfo for a value accepted by Storage.Set.
+func newValueInfo(val any, file int8) (ValueInfo, error) {
+	switch v := val.(type) {
+	case string:
+		return ValueInfo{File: file, Value: v, Kind: KindString}, nil
+	case Marker:
+		switch v {
+		case Nil:
+			return ValueInfo{File: file, Value: v.String(), Kind: KindNil}, nil
+		case EmptyMap:
+			return ValueInfo{File: file, Value: v.String(), Kind: KindEmptyMap}, nil
+		case EmptySlice:
+			return ValueInfo{File: file, Value: v.String(), Kind: KindEmptySlice}, nil
+		default:
+			return ValueInfo{}, util.FormatError(nil, "invalid marker %s", v)
+		}
+	case bool:
+		return ValueInfo{File: file, Value: strconv.FormatBool(v), Kind: KindBool, Raw: v}, nil
+	case int:
+		return newIntValueInfo(int64(v), val, file), nil
+	case int8:
+		return newIntValueInfo(int64(v), val, file), nil
+	case int16:
+		return newIntValueInfo(int64(v), val, file), nil
+	case int32:
+		return newIntValueInfo(int64(v), val, file), nil
+	case int64:
+		return newIntValueInfo(v, val, file), nil
+	case uint:
+		return newUintValueInfo(uint64(v), val, file), nil
+	case uint8:
+		return newUintValueInfo(uint64(v), val, file), nil
+	case uint16:
+		return newUintValueInfo(uint64(v), val, file), nil
+	case uint32:
+		return newUintValueInfo(uint64(v), val, file), nil
+	case uint64:
+		return newUintValueInfo(v, val, file), nil
+	case float32:
+		s := strconv.FormatFloat(float64(v), 'f', -1, 32)
+		return ValueInfo{File: file, Value: s, Kind: KindFloat, Raw: v}, nil
+	case float64:
+		s := strconv.FormatFloat(v, 'f', -1, 64)
+		return ValueInfo{File: file, Value: s, Kind: KindFloat, Raw: v}, nil
+	case json.Number:
+		s := string(v)
+		if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return ValueInfo{File: file, Value: s, Kind: KindInt, Raw: v}, nil
+		}
+		if _, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return ValueInfo{File: file, Value: s, Kind: KindUint, Raw: v}, nil
+		}
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return ValueInfo{}, util.FormatError(nil, "invalid number %q", s)
+		}
+		return ValueInfo{File: file, Value: s, Kind: KindFloat, Raw: v}, nil
+	default:
+		return ValueInfo{}, util.FormatError(nil, "unsupported value type %T", val)
+	}
+}
+
+// newIntValueInfo creates a ValueInfo for a signed integer.
+func newIntValueInfo(i int64, raw any, file int8) ValueInfo {
+	return ValueInfo{File: file, Value: strconv.FormatInt(i, 10), Kind: KindInt, Raw: raw}
+}
+
+// newUintValueInfo creates a ValueInfo for an unsigned integer.
+func newUintValueInfo(u uint64, raw any, file int8) ValueInfo {
+	return ValueInfo{File: file, Value: strconv.FormatUint(u, 10), Kind: KindUint, Raw: raw}
+}
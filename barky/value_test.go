@@ -0,0 +1,78 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/go-spring/spring-base/testing/assert"
+)
+
+func TestValueInfo(t *testing.T) {
+
+	t.Run("kinds", func(t *testing.T) {
+		tests := []struct {
+			val   any
+			value string
+			kind  Kind
+			raw   any
+		}{
+			{"8080", "8080", KindString, nil},
+			{"[]", "[]", KindString, nil},
+			{true, "true", KindBool, true},
+			{8080, "8080", KindInt, 8080},
+			{int8(-8), "-8", KindInt, int8(-8)},
+			{int64(-1 << 40), "-1099511627776", KindInt, int64(-1 << 40)},
+			{uint(7), "7", KindUint, uint(7)},
+			{uint64(1 << 63), "9223372036854775808", KindUint, uint64(1 << 63)},
+			{float32(0.1), "0.1", KindFloat, float32(0.1)},
+			{3.14, "3.14", KindFloat, 3.14},
+			{1e21, "1000000000000000000000", KindFloat, 1e21},
+			{json.Number("42"), "42", KindInt, json.Number("42")},
+			{json.Number("18446744073709551615"), "18446744073709551615", KindUint, json.Number("18446744073709551615")},
+			{json.Number("1.50"), "1.50", KindFloat, json.Number("1.50")},
+			{Nil, "<nil>", KindNil, nil},
+			{EmptyMap, "{}", KindEmptyMap, nil},
+			{EmptySlice, "[]", KindEmptySlice, nil},
+		}
+		for _, tt := range tests {
+			v, err := newValueInfo(tt.val, 1)
+			assert.That(t, err).Nil()
+			assert.That(t, v).Equal(ValueInfo{File: 1, Value: tt.value, Kind: tt.kind, Raw: tt.raw})
+		}
+
+		_, err := newValueInfo(json.Number("abc"), 0)
+		assert.Error(t, err).Matches(`invalid number "abc"`)
+		_, err = newValueInfo([]string{"a"}, 0)
+		assert.Error(t, err).Matches(`unsupported value type \[\]string`)
+	})
+
+	t.Run("typed", func(t *testing.T) {
+		assert.That(t, ValueInfo{Value: "a"}.Typed()).Equal("a")
+		assert.That(t, ValueInfo{Value: "8", Kind: KindInt, Raw: 8}.Typed()).Equal(8)
+		assert.That(t, ValueInfo{Value: "<nil>", Kind: KindNil}.Typed()).Nil()
+		assert.That(t, ValueInfo{Value: "{}", Kind: KindEmptyMap}.Typed()).Equal(map[string]any{})
+		assert.That(t, ValueInfo{Value: "[]", Kind: KindEmptySlice}.Typed()).Equal([]any{})
+	})
+
+	t.Run("kind string", func(t *testing.T) {
+		assert.That(t, KindFloat.String()).Equal("float")
+		assert.That(t, KindEmptySlice.String()).Equal("empty slice")
+		assert.That(t, Kind(100).String()).Equal("Kind(100)")
+	})
+}
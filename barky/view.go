@@ -0,0 +1,124 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"slices"
+	"strings"
+)
+
+// View is a read-only view of a Storage scoped to a key prefix.
+// All keys passed to and returned by a View are relative to its prefix,
+// e.g. "brokers[0]" in the view "mq.kafka" refers to "mq.kafka.brokers[0]".
+// The view is backed by the Storage itself, so it reflects later changes
+// without copying any data. Errors keep reporting absolute paths.
+type View struct {
+	s      *Storage
+	prefix string
+}
+
+// Sub returns a read-only view of the subtree under the given prefix.
+// An empty prefix yields a view of the whole Storage.
+func (s *Storage) Sub(prefix string) *View {
+	return &View{s: s, prefix: prefix}
+}
+
+// Sub returns a view of the subtree under the given relative prefix.
+func (v *View) Sub(prefix string) *View {
+	return &View{s: v.s, prefix: v.Abs(prefix)}
+}
+
+// Prefix returns the absolute prefix of the view.
+func (v *View) Prefix() string {
+	return v.prefix
+}
+
+// Abs converts a key relative to the view into an absolute key.
+// The empty key refers to the prefix itself.
+func (v *View) Abs(key string) string {
+	switch {
+	case v.prefix == "":
+		return key
+	case key == "":
+		return v.prefix
+	case key[0] == '[':
+		return v.prefix + key
+	default:
+		return v.prefix + "." + key
+	}
+}
+
+// rel converts an absolute key under the prefix into a relative key.
+func (v *View) rel(key string) string {
+	if v.prefix == "" {
+		return key
+	}
+	return strings.TrimPrefix(key[len(v.prefix):], ".")
+}
+
+// RawFile exposes the file name → index mapping of the underlying Storage,
+// so that the File of a ValueInfo can be resolved.
+func (v *View) RawFile() map[string]int8 {
+	return v.s.RawFile()
+}
+
+// Keys returns all relative keys of the values under the prefix,
+// sorted lexicographically for consistent iteration.
+func (v *View) Keys() []string {
+	var keys []string
+	for key, val := range v.s.Walk(v.prefix) {
+		if val.IsEmpty() || key == v.prefix {
+			continue
+		}
+		keys = append(keys, v.rel(key))
+	}
+	if keys == nil {
+		return []string{}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// SubKeys returns the immediate child keys under the given relative path.
+// See Storage.SubKeys for details.
+func (v *View) SubKeys(key string) ([]string, error) {
+	return v.s.SubKeys(v.Abs(key))
+}
+
+// Has checks whether the given relative key exists in the view.
+// The empty key reports whether the prefix itself exists; for a view of
+// the whole Storage, whether the Storage holds any key.
+func (v *View) Has(key string) bool {
+	if key != "" {
+		return v.s.Has(v.Abs(key))
+	}
+	if v.prefix == "" {
+		return v.s.root != nil && len(v.s.root.Data) > 0
+	}
+	return v.s.Has(v.prefix)
+}
+
+// Lookup returns the ValueInfo stored under the given relative key.
+func (v *View) Lookup(key string) (ValueInfo, bool) {
+	return v.s.Lookup(v.Abs(key))
+}
+
+// Get retrieves the value associated with the given relative key.
+// See Storage.Get for details.
+func (v *View) Get(key string, def ...string) string {
+	return v.s.Get(v.Abs(key), def...)
+}
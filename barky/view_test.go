@@ -0,0 +1,105 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"testing"
+
+	"github.com/go-spring/spring-base/testing/assert"
+)
+
+func TestView(t *testing.T) {
+	s := NewStorage()
+	fileID := s.AddFile("app.yaml")
+	for k, val := range map[string]any{
+		"mq.kafka.brokers[0]": "a:9092",
+		"mq.kafka.brokers[1]": "b:9092",
+		"mq.kafka.topic":      "orders",
+		"mq.kafka.opts":       EmptyMap,
+		"mq.rabbit.host":      "r",
+		"servers[0].host":     "h0",
+		"servers[1].host":     "h1",
+	} {
+		assert.That(t, s.Set(k, val, fileID)).Nil()
+	}
+
+	t.Run("map prefix", func(t *testing.T) {
+		v := s.Sub("mq.kafka")
+		assert.That(t, v.Prefix()).Equal("mq.kafka")
+		assert.That(t, v.Get("topic")).Equal("orders")
+		assert.That(t, v.Get("brokers[1]")).Equal("b:9092")
+		assert.That(t, v.Get("missing", "def")).Equal("def")
+		assert.That(t, v.Has("")).True()
+		assert.That(t, v.Has("brokers")).True()
+		assert.That(t, v.Has("opts")).True()
+		assert.That(t, v.Has("host")).False()
+
+		info, ok := v.Lookup("topic")
+		assert.That(t, ok).True()
+		assert.That(t, info.File).Equal(v.RawFile()["app.yaml"])
+
+		keys := v.Keys()
+		assert.That(t, keys).Equal([]string{"brokers[0]", "brokers[1]", "topic"})
+
+		subKeys, err := v.SubKeys("")
+		assert.That(t, err).Nil()
+		assert.That(t, subKeys).Equal([]string{"brokers", "opts", "topic"})
+
+		_, err = v.SubKeys("topic")
+		assert.Error(t, err).Matches("property conflict at path mq.kafka.topic")
+	})
+
+	t.Run("array prefix", func(t *testing.T) {
+		v := s.Sub("servers")
+		assert.That(t, v.Get("[0].host")).Equal("h0")
+		assert.That(t, v.Keys()).Equal([]string{"[0].host", "[1].host"})
+
+		v = v.Sub("[1]")
+		assert.That(t, v.Prefix()).Equal("servers[1]")
+		assert.That(t, v.Get("host")).Equal("h1")
+	})
+
+	t.Run("nested and root views", func(t *testing.T) {
+		v := s.Sub("mq").Sub("rabbit")
+		assert.That(t, v.Prefix()).Equal("mq.rabbit")
+		assert.That(t, v.Keys()).Equal([]string{"host"})
+
+		root := s.Sub("")
+		assert.That(t, root.Keys()).Equal(s.Keys())
+		assert.That(t, root.Get("mq.rabbit.host")).Equal("r")
+		assert.That(t, root.Has("")).True()
+		assert.That(t, s.Sub("mq").Has("")).True()
+		assert.That(t, s.Sub("mq").Sub("").Has("")).True()
+		assert.That(t, NewStorage().Sub("").Has("")).False()
+	})
+
+	t.Run("leaf and missing prefix", func(t *testing.T) {
+		v := s.Sub("mq.kafka.topic")
+		assert.That(t, v.Keys()).Equal([]string{})
+		assert.That(t, v.Get("")).Equal("orders")
+
+		v = s.Sub("nothing")
+		assert.That(t, v.Has("")).False()
+		assert.That(t, v.Keys()).Equal([]string{})
+	})
+
+	t.Run("reflects later changes", func(t *testing.T) {
+		v := s.Sub("mq.kafka")
+		assert.That(t, s.Set("mq.kafka.group", "g1", fileID)).Nil()
+		assert.That(t, v.Get("group")).Equal("g1")
+	})
+}
@@ -0,0 +1,251 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"slices"
+
+	"github.com/go-spring/spring-base/util"
+	"gopkg.in/yaml.v3"
+)
+
+// maxYAMLAliases limits the alias expansions of a YAML document, so that
+// nested aliases cannot blow a small document up exponentially.
+const maxYAMLAliases = 10000
+
+// yamlFrame is an open mapping or sequence of a YAMLTokenReader.
+type yamlFrame struct {
+	node    *yaml.Node
+	content []*yaml.Node // children; key/value pairs for mappings
+	pos     int          // index of the next child
+}
+
+// YAMLTokenReader is a TokenReader that translates the events of a YAML
+// stream into JSON-style tokens: mapping and sequence start and end
+// events become json.Delim tokens, and scalars are typed after their
+// resolved tag (!!str gives a string, !!bool a bool, !!int and !!float
+// a json.Number, !!null nil). Aliases are expanded and merge keys
+// ("<<") are applied, with the mapping's own keys taking precedence. An
+// alias to an enclosing node, more than maxYAMLAliases expansions per
+// document, and mapping keys that are not scalars are errors.
+//
+// The documents of a multi-document stream are read one after another,
+// so FlattenTokens can be called repeatedly until it returns io.EOF.
+// Each document is parsed as a whole before its tokens are produced, so
+// memory is bounded by the largest document rather than by nesting depth
+// as with JSON; empty and null documents are skipped.
+type YAMLTokenReader struct {
+	d       *yaml.Decoder
+	stack   []yamlFrame
+	aliases int // alias expansions in the current document
+}
+
+// NewYAMLTokenReader creates a YAMLTokenReader that reads from r.
+func NewYAMLTokenReader(r io.Reader) *YAMLTokenReader {
+	return &YAMLTokenReader{d: yaml.NewDecoder(r)}
+}
+
+// Token returns the next token, or io.EOF at the end of the stream.
+func (y *YAMLTokenReader) Token() (json.Token, error) {
+	for len(y.stack) == 0 {
+		var doc yaml.Node
+		if err := y.d.Decode(&doc); err != nil {
+			return nil, err
+		}
+		if len(doc.Content) > 0 && doc.Content[0].ShortTag() != "!!null" {
+			y.aliases = 0
+			return y.enter(doc.Content[0])
+		}
+	}
+	top := &y.stack[len(y.stack)-1]
+	if top.pos == len(top.content) {
+		mapping := top.node.Kind == yaml.MappingNode
+		y.stack = y.stack[:len(y.stack)-1]
+		if mapping {
+			return json.Delim('}'), nil
+		}
+		return json.Delim(']'), nil
+	}
+	n := top.content[top.pos]
+	top.pos++
+	if top.node.Kind == yaml.MappingNode && top.pos%2 == 1 {
+		k, err := y.resolveAlias(n)
+		if err != nil {
+			return nil, err
+		}
+		if k.Kind != yaml.ScalarNode {
+			return nil, util.FormatError(nil, "yaml line %d: mapping key must be a scalar", n.Line)
+		}
+		return k.Value, nil
+	}
+	return y.enter(n)
+}
+
+// enter returns the token that opens n, pushing a frame if n is a
+// mapping or a sequence.
+func (y *YAMLTokenReader) enter(n *yaml.Node) (json.Token, error) {
+	n, err := y.resolveAlias(n)
+	if err != nil {
+		return nil, err
+	}
+	switch n.Kind {
+	case yaml.MappingNode:
+		y.stack = append(y.stack, yamlFrame{node: n})
+		content, err := y.mergeContent(n, nil)
+		if err != nil {
+			return nil, err
+		}
+		y.stack[len(y.stack)-1].content = content
+		return json.Delim('{'), nil
+	case yaml.SequenceNode:
+		y.stack = append(y.stack, yamlFrame{node: n, content: n.Content})
+		return json.Delim('['), nil
+	default:
+		return yamlScalar(n)
+	}
+}
+
+// resolveAlias follows n to the node it refers to if n is an alias. It
+// returns an error if that node encloses n, or if the document has too
+// many alias expansions.
+func (y *YAMLTokenReader) resolveAlias(n *yaml.Node) (*yaml.Node, error) {
+	if n.Kind != yaml.AliasNode {
+		return n, nil
+	}
+	if y.aliases++; y.aliases > maxYAMLAliases {
+		return nil, util.FormatError(nil, "yaml line %d: too many alias expansions", n.Line)
+	}
+	target := n
+	for target.Kind == yaml.AliasNode {
+		target = target.Alias
+	}
+	for _, f := range y.stack {
+		if f.node == target {
+			return nil, util.FormatError(nil, "yaml line %d: alias *%s refers to an enclosing node", n.Line, n.Value)
+		}
+	}
+	return target, nil
+}
+
+// mergeContent returns the key/value pairs of the mapping n with its
+// merge keys applied. The mapping's own keys override merged ones, and
+// among merged mappings the first one listed wins. chain holds the
+// mappings whose merge keys led to n.
+func (y *YAMLTokenReader) mergeContent(n *yaml.Node, chain []*yaml.Node) ([]*yaml.Node, error) {
+	var merged []*yaml.Node
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Tag != "!!merge" {
+			continue
+		}
+		v, err := y.resolveAlias(n.Content[i+1])
+		if err != nil {
+			return nil, err
+		}
+		if v.Kind != yaml.SequenceNode {
+			merged = append(merged, v)
+			continue
+		}
+		for _, c := range v.Content {
+			if c, err = y.resolveAlias(c); err != nil {
+				return nil, err
+			}
+			merged = append(merged, c)
+		}
+	}
+	if merged == nil {
+		return n.Content, nil
+	}
+
+	chain = append(chain, n)
+	seen := make(map[string]bool)
+	var content []*yaml.Node
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if k := n.Content[i]; k.Tag != "!!merge" {
+			seen[aliasTarget(k).Value] = true
+		}
+	}
+	for _, m := range merged {
+		if m.Kind != yaml.MappingNode {
+			continue
+		}
+		if slices.Contains(chain, m) {
+			return nil, util.FormatError(nil, "yaml line %d: mapping merges itself", m.Line)
+		}
+		c, err := y.mergeContent(m, chain)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i+1 < len(c); i += 2 {
+			if k := aliasTarget(c[i]).Value; !seen[k] {
+				seen[k] = true
+				content = append(content, c[i], c[i+1])
+			}
+		}
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Tag != "!!merge" {
+			content = append(content, n.Content[i], n.Content[i+1])
+		}
+	}
+	return content, nil
+}
+
+// aliasTarget follows n to the node it refers to if n is an alias.
+func aliasTarget(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+// yamlScalar converts a scalar node into a token after its resolved tag.
+// Numbers that are valid JSON numbers keep their textual form, others
+// (e.g. "0x1F" or "1_000") are normalized; infinities and NaN become
+// float64 values.
+func yamlScalar(n *yaml.Node) (json.Token, error) {
+	switch n.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var b bool
+		if err := n.Decode(&b); err != nil {
+			return nil, err
+		}
+		return b, nil
+	case "!!int", "!!float":
+		if json.Valid([]byte(n.Value)) {
+			return json.Number(n.Value), nil
+		}
+		var v any
+		if err := n.Decode(&v); err != nil {
+			return nil, err
+		}
+		if f, ok := v.(float64); ok && (math.IsInf(f, 0) || math.IsNaN(f)) {
+			return f, nil
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(b), nil
+	default:
+		return n.Value, nil
+	}
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package barky
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/spring-base/testing/assert"
+)
+
+func TestYAMLTokenReader(t *testing.T) {
+
+	tokens := func(s string) ([]json.Token, error) {
+		var toks []json.Token
+		y := NewYAMLTokenReader(strings.NewReader(s))
+		for {
+			tok, err := y.Token()
+			if errors.Is(err, io.EOF) {
+				return toks, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, tok)
+		}
+	}
+
+	t.Run("events", func(t *testing.T) {
+		toks, err := tokens("a: [1, x]\nb: {}\n")
+		assert.That(t, err).Nil()
+		assert.That(t, toks).Equal([]json.Token{
+			json.Delim('{'),
+			"a", json.Delim('['), json.Number("1"), "x", json.Delim(']'),
+			"b", json.Delim('{'), json.Delim('}'),
+			json.Delim('}'),
+		})
+	})
+
+	t.Run("scalar tags", func(t *testing.T) {
+		toks, err := tokens(`[true, "true", 1.50, 0x1F, 1_000, .inf, ~, null, "1", 2024-01-02]`)
+		assert.That(t, err).Nil()
+		assert.That(t, toks[1:6]).Equal([]json.Token{
+			true, "true", json.Number("1.50"), json.Number("31"), json.Number("1000"),
+		})
+		assert.That(t, math.IsInf(toks[6].(float64), 1)).True()
+		assert.That(t, toks[7:11]).Equal([]json.Token{nil, nil, "1", "2024-01-02"})
+	})
+
+	t.Run("malformed document", func(t *testing.T) {
+		_, err := tokens("a: [1, 2\n")
+		assert.That(t, err).NotNil()
+	})
+	t.Run("recursive aliases", func(t *testing.T) {
+		_, err := tokens("a: &x\n  - b: *x\n")
+		assert.Error(t, err).Matches(`yaml line 2: alias \*x refers to an enclosing node`)
+
+		_, err = tokens("a: &x\n  b: 1\n  <<: *x\n")
+		assert.Error(t, err).Matches(`alias \*x refers to an enclosing node`)
+	})
+
+	t.Run("too many aliases", func(t *testing.T) {
+		var sb strings.Builder
+		sb.WriteString("a0: &a0 [x, x, x, x, x, x, x, x, x, x]\n")
+		for i := 1; i < 10; i++ {
+			fmt.Fprintf(&sb, "a%d: &a%d [", i, i)
+			for j := range 10 {
+				if j > 0 {
+					sb.WriteString(", ")
+				}
+				fmt.Fprintf(&sb, "*a%d", i-1)
+			}
+			sb.WriteString("]\n")
+		}
+		_, err := tokens(sb.String())
+		assert.Error(t, err).Matches("too many alias expansions")
+	})
+
+	t.Run("non-scalar keys", func(t *testing.T) {
+		_, err := tokens("? [a, b]\n: 1\n")
+		assert.Error(t, err).Matches("yaml line 1: mapping key must be a scalar")
+
+		_, err = tokens("k: &k {a: 1}\nm: {*k : 2}\n")
+		assert.Error(t, err).Matches("yaml line 2: mapping key must be a scalar")
+	})
+}
+
+func TestFlattenYAML(t *testing.T) {
+
+	collect := func(s string) ([]map[string]any, error) {
+		var docs []map[string]any
+		y := NewYAMLTokenReader(strings.NewReader(s))
+		for {
+			m := make(map[string]any)
+			err := FlattenTokens(y, func(key string, value any) error {
+				m[key] = value
+				return nil
+			})
+			if errors.Is(err, io.EOF) {
+				return docs, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, m)
+		}
+	}
+
+	t.Run("same as FlattenMap", func(t *testing.T) {
+		docs, err := collect(`
+a: "123"
+b: 4.5
+c: true
+d: null
+arr: [abc, {x: 1, y: [1, 2]}, null, [], {}]
+map: {nil: ~, empty_arr: [], empty_map: {}}
+`)
+		assert.That(t, err).Nil()
+		assert.That(t, docs).Equal([]map[string]any{{
+			"a":             "123",
+			"b":             "4.5",
+			"c":             "true",
+			"d":             Nil,
+			"arr[0]":        "abc",
+			"arr[1].x":      "1",
+			"arr[1].y[0]":   "1",
+			"arr[1].y[1]":   "2",
+			"arr[2]":        Nil,
+			"arr[3]":        EmptySlice,
+			"arr[4]":        EmptyMap,
+			"map.nil":       Nil,
+			"map.empty_arr": EmptySlice,
+			"map.empty_map": EmptyMap,
+		}})
+	})
+
+	t.Run("multiple documents", func(t *testing.T) {
+		docs, err := collect("a: 1\n---\n---\nb: [x]\n")
+		assert.That(t, err).Nil()
+		assert.That(t, docs).Equal([]map[string]any{
+			{"a": "1"},
+			{"b[0]": "x"},
+		})
+	})
+
+	t.Run("aliases and merge keys", func(t *testing.T) {
+		docs, err := collect(`
+base: &base {host: localhost, port: 80, tls: {on: true}}
+extra: &extra {port: 81, debug: true}
+dev:
+  <<: [*base, *extra]
+  tls: {on: false}
+hosts: [&h a, *h]
+`)
+		assert.That(t, err).Nil()
+		assert.That(t, docs[0]).Equal(map[string]any{
+			"base.host":   "localhost",
+			"base.port":   "80",
+			"base.tls.on": "true",
+			"extra.port":  "81",
+			"extra.debug": "true",
+			"dev.host":    "localhost",
+			"dev.port":    "80",
+			"dev.debug":   "true",
+			"dev.tls.on":  "false",
+			"hosts[0]":    "a",
+			"hosts[1]":    "a",
+		})
+	})
+
+	t.Run("scalar document", func(t *testing.T) {
+		_, err := collect("abc\n")
+		assert.Error(t, err).Matches("document must be an object or an array")
+	})
+}
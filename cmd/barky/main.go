@@ -0,0 +1,417 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Command barky flattens, queries, diffs and checks configuration files.
+//
+// Usage:
+//
+//	barky flatten [-format text|json] [-show-source] FILE...
+//	barky get [-format text|json] [-show-source] KEY FILE...
+//	barky query [-format text|json] [-show-source] PATTERN FILE...
+//	barky diff [-format text|json] LEFT RIGHT
+//	barky check [-format text|json] FILE...
+//
+// Files are loaded in order into one Storage, so later files override
+// earlier ones, and files listed under "spring.config.import" are loaded
+// too. JSON (.json), YAML (.yaml, .yml) and TOML (.toml) files are
+// supported. LEFT and RIGHT of diff are comma-separated lists of files.
+// PATTERN accepts the wildcards "*", "[*]" and "**".
+//
+// The exit status is 0 on success, 1 if get or query found nothing,
+// diff found differences or check found conflicts, and 2 on errors.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-spring/spring-base/barky"
+	"github.com/go-spring/spring-base/util"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Exit statuses.
+const (
+	exitOK    = 0 // Success.
+	exitFound = 1 // Nothing found, differences or conflicts.
+	exitError = 2 // Invalid usage or failure.
+)
+
+const usage = `usage:
+  barky flatten [-format text|json] [-show-source] FILE...
+  barky get [-format text|json] [-show-source] KEY FILE...
+  barky query [-format text|json] [-show-source] PATTERN FILE...
+  barky diff [-format text|json] LEFT RIGHT
+  barky check [-format text|json] FILE...
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// command is a subcommand. It returns the exit status.
+type command func(c *cli, args []string) (int, error)
+
+var commands = map[string]command{
+	"flatten": (*cli).flatten,
+	"get":     (*cli).get,
+	"query":   (*cli).query,
+	"diff":    (*cli).diff,
+	"check":   (*cli).check,
+}
+
+// cli holds the output streams and the common flags of a subcommand.
+type cli struct {
+	stdout     io.Writer
+	stderr     io.Writer
+	format     string
+	showSource bool
+}
+
+// run runs the command line and returns the exit status.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitError
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "barky: unknown command %q\n%s", args[0], usage)
+		return exitError
+	}
+
+	c := &cli{stdout: stdout, stderr: stderr}
+	fs := flag.NewFlagSet("barky "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&c.format, "format", "text", "output format: text or json")
+	switch args[0] {
+	case "flatten", "get", "query":
+		fs.BoolVar(&c.showSource, "show-source", false, "show the file that set each value")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitError
+	}
+	if c.format != "text" && c.format != "json" {
+		fmt.Fprintf(stderr, "barky: unknown format %q\n", c.format)
+		return exitError
+	}
+
+	code, err := cmd(c, fs.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "barky %s: %v\n", args[0], err)
+		return exitError
+	}
+	return code
+}
+
+// newLoader creates a Loader for s that understands JSON, YAML and TOML.
+func newLoader(s *barky.Storage) *barky.Loader {
+	l := barky.NewLoader(s)
+	l.KeepTypes = true
+	l.RegisterDecoder(".toml", decodeTOML)
+	return l
+}
+
+// decodeTOML decodes a TOML document.
+func decodeTOML(data []byte) ([]map[string]any, error) {
+	var m map[string]any
+	if err := toml.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return []map[string]any{m}, nil
+}
+
+// load loads the files in order into a new Storage.
+func load(files []string) (*barky.Storage, error) {
+	if len(files) == 0 {
+		return nil, util.FormatError(nil, "no files given")
+	}
+	s := barky.NewStorage()
+	l := newLoader(s)
+	for _, file := range files {
+		if err := l.Load(file); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// entry is a flattened value and the file that set it.
+type entry struct {
+	key    string
+	value  barky.ValueInfo
+	source string
+}
+
+// sourceName returns the name of the file with the given index.
+func sourceName(s *barky.Storage, idx int8) string {
+	for name, i := range s.RawFile() {
+		if i == idx {
+			return name
+		}
+	}
+	return ""
+}
+
+// jsonValue is the JSON form of an entry with its source.
+type jsonValue struct {
+	Value  any    `json:"value"`
+	Source string `json:"source"`
+}
+
+// printEntries prints flattened values, as "key=value" lines or as
+// a JSON object keyed by the flattened keys.
+func (c *cli) printEntries(entries []entry) error {
+	if c.format == "json" {
+		m := make(map[string]any, len(entries))
+		for _, e := range entries {
+			if c.showSource {
+				m[e.key] = jsonValue{Value: e.value.Typed(), Source: e.source}
+			} else {
+				m[e.key] = e.value.Typed()
+			}
+		}
+		return c.printJSON(m)
+	}
+	for _, e := range entries {
+		line := e.key + "=" + e.value.Value
+		if c.showSource {
+			line += "\t# " + e.source
+		}
+		if _, err := fmt.Fprintln(c.stdout, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printJSON prints v as indented JSON.
+func (c *cli) printJSON(v any) error {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// flatten prints the flattened keys of the merged files.
+func (c *cli) flatten(args []string) (int, error) {
+	s, err := load(args)
+	if err != nil {
+		return exitError, err
+	}
+	var entries []entry
+	for key, v := range s.Walk("") {
+		entries = append(entries, entry{key, v, sourceName(s, v.File)})
+	}
+	return exitOK, c.printEntries(entries)
+}
+
+// get prints the value of a key, or the values under it if the key
+// refers to a map or an array.
+func (c *cli) get(args []string) (int, error) {
+	if len(args) < 1 {
+		return exitError, util.FormatError(nil, "missing key")
+	}
+	key := args[0]
+	if _, err := barky.SplitPath(key); err != nil {
+		return exitError, err
+	}
+	s, err := load(args[1:])
+	if err != nil {
+		return exitError, err
+	}
+
+	if v, ok := s.Lookup(key); ok {
+		source := sourceName(s, v.File)
+		if c.format == "json" {
+			if c.showSource {
+				return exitOK, c.printJSON(jsonValue{Value: v.Typed(), Source: source})
+			}
+			return exitOK, c.printJSON(v.Typed())
+		}
+		line := v.Value
+		if c.showSource {
+			line += "\t# " + source
+		}
+		_, err = fmt.Fprintln(c.stdout, line)
+		return exitOK, err
+	}
+
+	var entries []entry
+	for k, v := range s.Walk(key) {
+		entries = append(entries, entry{k, v, sourceName(s, v.File)})
+	}
+	if len(entries) == 0 {
+		fmt.Fprintf(c.stderr, "barky get: key %s not found\n", key)
+		return exitFound, nil
+	}
+	return exitOK, c.printEntries(entries)
+}
+
+// query prints the values whose keys match a pattern.
+func (c *cli) query(args []string) (int, error) {
+	if len(args) < 1 {
+		return exitError, util.FormatError(nil, "missing pattern")
+	}
+	p, err := barky.CompilePattern(args[0])
+	if err != nil {
+		return exitError, err
+	}
+	s, err := load(args[1:])
+	if err != nil {
+		return exitError, err
+	}
+	var entries []entry
+	for key, v := range s.Match(p) {
+		entries = append(entries, entry{key, v, sourceName(s, v.File)})
+	}
+	if err = c.printEntries(entries); err != nil {
+		return exitError, err
+	}
+	if len(entries) == 0 {
+		return exitFound, nil
+	}
+	return exitOK, nil
+}
+
+// change is the JSON form of a changed value.
+type change struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// diff prints the differences between two sets of files.
+func (c *cli) diff(args []string) (int, error) {
+	if len(args) != 2 {
+		return exitError, util.FormatError(nil, "expect two comma-separated file lists")
+	}
+	left, err := load(strings.Split(args[0], ","))
+	if err != nil {
+		return exitError, err
+	}
+	right, err := load(strings.Split(args[1], ","))
+	if err != nil {
+		return exitError, err
+	}
+
+	l, r := left.RawData(), right.RawData()
+	keys := make(map[string]struct{}, len(l)+len(r))
+	for key := range l {
+		keys[key] = struct{}{}
+	}
+	for key := range r {
+		keys[key] = struct{}{}
+	}
+
+	var (
+		added   = make(map[string]any)
+		removed = make(map[string]any)
+		changed = make(map[string]change)
+		lines   []string
+	)
+	for _, key := range util.OrderedMapKeys(keys) {
+		lv, inLeft := l[key]
+		rv, inRight := r[key]
+		switch {
+		case !inLeft:
+			added[key] = rv.Typed()
+			lines = append(lines, "+ "+key+"="+rv.Value)
+		case !inRight:
+			removed[key] = lv.Typed()
+			lines = append(lines, "- "+key+"="+lv.Value)
+		case lv.Value != rv.Value || lv.Kind != rv.Kind:
+			changed[key] = change{Old: lv.Typed(), New: rv.Typed()}
+			lines = append(lines, "~ "+key+": "+lv.Value+" -> "+rv.Value)
+		}
+	}
+
+	if c.format == "json" {
+		err = c.printJSON(map[string]any{
+			"added":   added,
+			"removed": removed,
+			"changed": changed,
+		})
+	} else {
+		for _, line := range lines {
+			if _, err = fmt.Fprintln(c.stdout, line); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return exitError, err
+	}
+	if len(lines) > 0 {
+		return exitFound, nil
+	}
+	return exitOK, nil
+}
+
+// jsonConflict is the JSON form of a barky.ConflictError.
+type jsonConflict struct {
+	Path          string `json:"path"`
+	Existing      string `json:"existing"`
+	Attempted     string `json:"attempted"`
+	ExistingFile  string `json:"existingFile,omitempty"`
+	AttemptedFile string `json:"attemptedFile,omitempty"`
+}
+
+// check reports every structural conflict between the files. Keys
+// defined by more than one file are overrides, not conflicts.
+func (c *cli) check(args []string) (int, error) {
+	if len(args) == 0 {
+		return exitError, util.FormatError(nil, "no files given")
+	}
+	l := newLoader(barky.NewStorage())
+	l.CollectConflicts = true
+	var conflicts barky.ConflictErrors
+	for _, file := range args {
+		err := l.Load(file)
+		var errs barky.ConflictErrors
+		if errors.As(err, &errs) {
+			conflicts = append(conflicts, errs...)
+		} else if err != nil {
+			return exitError, err
+		}
+	}
+
+	if c.format == "json" {
+		list := make([]jsonConflict, 0, len(conflicts))
+		for _, e := range conflicts {
+			list = append(list, jsonConflict(*e))
+		}
+		if err := c.printJSON(list); err != nil {
+			return exitError, err
+		}
+	} else {
+		for _, e := range conflicts {
+			if _, err := fmt.Fprintln(c.stdout, e.Error()); err != nil {
+				return exitError, err
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return exitFound, nil
+	}
+	return exitOK, nil
+}
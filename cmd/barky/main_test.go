@@ -0,0 +1,190 @@
+/*
+ * Copyright 2025 The Go-Spring Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/spring-base/testing/assert"
+)
+
+// setup writes the test files into a temporary directory and returns
+// a function that resolves file names in it.
+func setup(t *testing.T) func(names ...string) string {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"app.yaml": "name: app\nport: 8080\ndb:\n  hosts: [a, b]\n---\ndebug: true\n",
+		"dev.toml": "port = 9090\n[db]\nuser = \"root\"\n",
+		"app.json": `{"name": "app", "port": 9090, "db": {"hosts": ["a", "c"], "user": "root"}, "tags": []}`,
+		"bad.json": `{"db": "x", "tags": [1]}`,
+	} {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		assert.That(t, err).Nil()
+	}
+	return func(names ...string) string {
+		for i, name := range names {
+			names[i] = filepath.Join(dir, name)
+		}
+		return strings.Join(names, ",")
+	}
+}
+
+// runCLI runs the command line and returns the exit status and output.
+func runCLI(args ...string) (int, string, string) {
+	var stdout, stderr strings.Builder
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestFlatten(t *testing.T) {
+	file := setup(t)
+
+	code, out, _ := runCLI("flatten", file("app.yaml"), file("dev.toml"))
+	assert.That(t, code).Equal(exitOK)
+	assert.String(t, out).Equal("db.hosts[0]=a\ndb.hosts[1]=b\ndb.user=root\ndebug=true\nname=app\nport=9090\n")
+
+	code, out, _ = runCLI("flatten", "-show-source", file("app.yaml"), file("dev.toml"))
+	assert.That(t, code).Equal(exitOK)
+	assert.String(t, out).Matches("db.user=root\t# .*dev.toml\ndebug=true\t# .*app.yaml\n")
+
+	code, out, _ = runCLI("flatten", "-format", "json", file("app.json"))
+	assert.That(t, code).Equal(exitOK)
+	assert.String(t, out).Equal(`{
+  "db.hosts[0]": "a",
+  "db.hosts[1]": "c",
+  "db.user": "root",
+  "name": "app",
+  "port": 9090,
+  "tags": []
+}
+`)
+
+	code, out, _ = runCLI("flatten", "-format", "json", "-show-source", file("dev.toml"))
+	assert.That(t, code).Equal(exitOK)
+	assert.String(t, out).Matches(`"port": \{\n    "value": 9090,\n    "source": ".*dev.toml"\n  \}`)
+}
+
+func TestGetQuery(t *testing.T) {
+	file := setup(t)
+
+	code, out, _ := runCLI("get", "port", file("app.yaml"), file("dev.toml"))
+	assert.That(t, code).Equal(exitOK)
+	assert.String(t, out).Equal("9090\n")
+
+	code, out, _ = runCLI("get", "-format", "json", "-show-source", "debug", file("app.yaml"))
+	assert.That(t, code).Equal(exitOK)
+	assert.String(t, out).Matches(`^\{\n  "value": true,\n  "source": ".*app.yaml"\n\}\n$`)
+
+	code, out, _ = runCLI("get", "db.hosts", file("app.yaml"))
+	assert.That(t, code).Equal(exitOK)
+	assert.String(t, out).Equal("db.hosts[0]=a\ndb.hosts[1]=b\n")
+
+	code, out, errOut := runCLI("get", "none", file("app.yaml"))
+	assert.That(t, code).Equal(exitFound)
+	assert.String(t, out).Equal("")
+	assert.String(t, errOut).Equal("barky get: key none not found\n")
+
+	code, out, _ = runCLI("query", "db.**", file("app.json"))
+	assert.That(t, code).Equal(exitOK)
+	assert.String(t, out).Equal("db.hosts[0]=a\ndb.hosts[1]=c\ndb.user=root\n")
+
+	code, out, _ = runCLI("query", "-show-source", "db.hosts[*]", file("app.yaml"))
+	assert.That(t, code).Equal(exitOK)
+	assert.String(t, out).Matches("^db.hosts\\[0\\]=a\t# .*app.yaml\ndb.hosts\\[1\\]=b\t# .*app.yaml\n$")
+
+	code, out, _ = runCLI("query", "-format", "json", "*.password", file("app.json"))
+	assert.That(t, code).Equal(exitFound)
+	assert.String(t, out).Equal("{}\n")
+}
+
+func TestDiff(t *testing.T) {
+	file := setup(t)
+
+	code, out, _ := runCLI("diff", file("app.yaml", "dev.toml"), file("app.json"))
+	assert.That(t, code).Equal(exitFound)
+	assert.String(t, out).Equal("~ db.hosts[1]: b -> c\n- debug=true\n+ tags=[]\n")
+
+	code, out, _ = runCLI("diff", "-format", "json", file("app.yaml"), file("app.yaml"))
+	assert.That(t, code).Equal(exitOK)
+	assert.String(t, out).Equal("{\n  \"added\": {},\n  \"changed\": {},\n  \"removed\": {}\n}\n")
+
+	code, out, _ = runCLI("diff", "-format", "json", file("dev.toml"), file("app.json"))
+	assert.That(t, code).Equal(exitFound)
+	assert.String(t, out).Matches(`"changed": \{\}`)
+	assert.String(t, out).Matches(`"name": "app"`)
+}
+
+func TestCheck(t *testing.T) {
+	file := setup(t)
+
+	code, out, _ := runCLI("check", file("app.yaml"))
+	assert.That(t, code).Equal(exitOK)
+	assert.String(t, out).Equal("")
+
+	code, out, _ = runCLI("check", file("app.yaml"), file("dev.toml"))
+	assert.That(t, code).Equal(exitOK)
+	assert.String(t, out).Equal("")
+
+	code, out, _ = runCLI("check", file("app.json"), file("bad.json"))
+	assert.That(t, code).Equal(exitFound)
+	assert.String(t, out).Matches(`^property conflict at path db: existing map \(from .*app.json\) vs value \(from .*bad.json\)
+property conflict at path tags\[0\]: existing value \(from .*app.json\) vs array \(from .*bad.json\)
+$`)
+
+	code, out, _ = runCLI("check", "-format", "json", file("app.json"), file("app.json"))
+	assert.That(t, code).Equal(exitOK)
+	assert.String(t, out).Equal("[]\n")
+
+	code, out, _ = runCLI("check", "-format", "json", file("app.json"), file("bad.json"))
+	assert.That(t, code).Equal(exitFound)
+	assert.String(t, out).Matches(`"path": "db",\n    "existing": "map",\n    "attempted": "value",\n    "existingFile": ".*app.json",\n    "attemptedFile": ".*bad.json"`)
+}
+
+func TestErrors(t *testing.T) {
+	file := setup(t)
+
+	for _, c := range []struct {
+		args []string
+		code int
+		msg  string
+	}{
+		{nil, exitError, "^usage:"},
+		{[]string{"help"}, exitError, `unknown command "help"`},
+		{[]string{"flatten", "-format", "xml", file("app.json")}, exitError, `unknown format "xml"`},
+		{[]string{"flatten", "-x"}, exitError, "flag provided but not defined: -x"},
+		{[]string{"flatten", "-h"}, exitOK, "Usage of barky flatten"},
+		{[]string{"flatten"}, exitError, "barky flatten: no files given"},
+		{[]string{"flatten", file("none.json")}, exitError, "no such file or directory"},
+		{[]string{"get"}, exitError, "missing key"},
+		{[]string{"get", "a[", file("app.json")}, exitError, "unclosed"},
+		{[]string{"query"}, exitError, "missing pattern"},
+		{[]string{"query", "a..b", file("app.json")}, exitError, "empty key segment"},
+		{[]string{"diff", file("app.json")}, exitError, "expect two comma-separated file lists"},
+		{[]string{"diff", file("none.json"), file("app.json")}, exitError, "no such file"},
+		{[]string{"diff", file("app.json"), file("none.json")}, exitError, "no such file"},
+		{[]string{"check"}, exitError, "no files given"},
+		{[]string{"check", file("none.json")}, exitError, "no such file"},
+		{[]string{"flatten", file("app.json") + ".txt"}, exitError, "no such file"},
+	} {
+		code, _, errOut := runCLI(c.args...)
+		assert.That(t, code).Equal(c.code)
+		assert.String(t, errOut).Matches(c.msg)
+	}
+}
@@ -2,4 +2,8 @@ module github.com/go-spring/spring-base
 
 go 1.24
 
-require github.com/spf13/cast v1.10.0
+require (
+	github.com/pelletier/go-toml/v2 v2.2.4
+	github.com/spf13/cast v1.10.0
+	gopkg.in/yaml.v3 v3.0.1
+)